@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// APIs a generated service can serve its entities through.
+const (
+	APIHTTP    = "http"    // JSON routes on the project's HTTP framework
+	APIGRPC    = "grpc"    // gRPC services described by a .proto file
+	APIGraphQL = "graphql" // One GraphQL endpoint on the project's HTTP framework
+)
+
+// apiNames lists the accepted --api values.
+var apiNames = []string{APIHTTP, APIGRPC, APIGraphQL}
+
+// checkAPI validates an --api value.
+func checkAPI(name string) error {
+	for _, api := range apiNames {
+		if name == api {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown API '%s': use one of %s", name, strings.Join(apiNames, ", "))
+}
+
+// serviceAPI returns the API a service recorded in the manifest serves.
+func serviceAPI(entry *ServiceEntry) string {
+	if entry.API == "" {
+		return APIHTTP
+	}
+	return entry.API
+}
+
+// protoPackageName is the protobuf package of a gRPC service, e.g. "line_items.v1".
+func protoPackageName(service string) string {
+	return strings.ToLower(snakeCase(service)) + ".v1"
+}
+
+// protoGoPackageName is the Go package protoc generates for a gRPC service, e.g. "lineitemsv1".
+func protoGoPackageName(service string) string {
+	return strings.ReplaceAll(strings.ToLower(snakeCase(service)), "_", "") + "v1"
+}
+
+// ProtoNumbers records the numbers the fields of an entity got in the .proto messages of a
+// gRPC service, so that adding or removing fields never renumbers the others. The Input
+// message uses them as they are; the entity message shifts them by protoNumberOffset.
+type ProtoNumbers struct {
+	Fields   map[string]int `yaml:"fields,omitempty"`   // Number of each field, by column
+	Reserved map[int]string `yaml:"reserved,omitempty"` // Column each number of a removed field belonged to
+}
+
+// protoNumberOffset is the number of fields the entity message declares before the entity's
+// own: id, created_at and updated_at.
+const protoNumberOffset = 3
+
+// assignProtoNumbers keeps the numbers of the fields already in prev, gives new fields the
+// next unused numbers in declaration order and reserves the numbers of removed fields.
+func assignProtoNumbers(prev ProtoNumbers, fields []EntityField) ProtoNumbers {
+	numbers := ProtoNumbers{Fields: map[string]int{}}
+	next := 1
+	reserve := func(n int, column string) {
+		if numbers.Reserved == nil {
+			numbers.Reserved = map[int]string{}
+		}
+		numbers.Reserved[n] = column
+	}
+	for n, column := range prev.Reserved {
+		reserve(n, column)
+		if n >= next {
+			next = n + 1
+		}
+	}
+	for _, n := range prev.Fields {
+		if n >= next {
+			next = n + 1
+		}
+	}
+
+	for _, f := range fields {
+		if n, ok := prev.Fields[f.Column()]; ok {
+			numbers.Fields[f.Column()] = n
+		} else {
+			numbers.Fields[f.Column()] = next
+			next++
+		}
+	}
+	for column, n := range prev.Fields {
+		if _, ok := numbers.Fields[column]; !ok {
+			reserve(n, column)
+		}
+	}
+	return numbers
+}
+
+// recordProtoNumbers stores the field numbers of the entities of a gRPC service in its
+// manifest entry. Entities no longer generated keep theirs.
+func recordProtoNumbers(s *ServiceEntry, entities []EntitySpec) {
+	if serviceAPI(s) != APIGRPC {
+		return
+	}
+	if s.ProtoFields == nil {
+		s.ProtoFields = map[string]ProtoNumbers{}
+	}
+	for _, e := range entities {
+		s.ProtoFields[e.Name] = e.protoNumbers()
+	}
+}
+
+// protoNumbers returns the numbers of the entity's fields, on top of the recorded ones.
+func (e EntitySpec) protoNumbers() ProtoNumbers {
+	return assignProtoNumbers(e.Proto, e.AllFields())
+}
+
+// ProtoNumber is the number of the field with the given column in the entity's Input
+// message, offset by offset, e.g. protoNumberOffset in the entity message.
+func (e EntitySpec) ProtoNumber(column string, offset int) int {
+	return e.protoNumbers().Fields[column] + offset
+}
+
+// ProtoReservedNumbers lists the numbers of removed fields, offset by offset, for a
+// reserved statement, e.g. "4, 7". It is empty when no field was removed.
+func (e EntitySpec) ProtoReservedNumbers(offset int) string {
+	var numbers []int
+	for n := range e.protoNumbers().Reserved {
+		numbers = append(numbers, n+offset)
+	}
+	sort.Ints(numbers)
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ProtoReservedNames lists the quoted names of removed fields that no current field took
+// over, for a reserved statement, e.g. `"legacy", "note"`.
+func (e EntitySpec) ProtoReservedNames() string {
+	numbers := e.protoNumbers()
+	var names []string
+	for _, column := range numbers.Reserved {
+		if _, ok := numbers.Fields[column]; !ok && !containsString(names, strconv.Quote(column)) {
+			names = append(names, strconv.Quote(column))
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// goCamelCase converts a snake_case protobuf field name into the Go field name
+// protoc-gen-go gives it, e.g. "customer_id" into "CustomerId".
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X') // A leading underscore would make the name unexported.
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// Dropped; the next letter is capitalized below.
+		case c >= '0' && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
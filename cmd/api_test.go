@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckAPI(t *testing.T) {
+	for _, api := range apiNames {
+		if err := checkAPI(api); err != nil {
+			t.Errorf("%s was rejected: %v", api, err)
+		}
+	}
+	if err := checkAPI("soap"); err == nil {
+		t.Error("an unknown API was accepted")
+	}
+}
+
+func TestProtoPackageNames(t *testing.T) {
+	tests := []struct{ service, pkg, goPkg string }{
+		{"orders", "orders.v1", "ordersv1"},
+		{"line-items", "line_items.v1", "lineitemsv1"},
+		{"orderItems", "order_items.v1", "orderitemsv1"},
+		{"ORDERS", "orders.v1", "ordersv1"},
+	}
+	for _, tt := range tests {
+		if got := protoPackageName(tt.service); got != tt.pkg {
+			t.Errorf("protoPackageName(%q) = %q, want %q", tt.service, got, tt.pkg)
+		}
+		if got := protoGoPackageName(tt.service); got != tt.goPkg {
+			t.Errorf("protoGoPackageName(%q) = %q, want %q", tt.service, got, tt.goPkg)
+		}
+	}
+}
+
+// The names must match the fields protoc-gen-go generates, or the servers do not compile.
+func TestGoCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"customer_email": "CustomerEmail",
+		"customer_id":    "CustomerId",
+		"address2":       "Address2",
+		"line_2_text":    "Line_2Text",
+		"_private":       "XPrivate",
+		"paid":           "Paid",
+	}
+	for in, want := range tests {
+		if got := goCamelCase(in); got != want {
+			t.Errorf("goCamelCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// protoEntity returns an entity named orders with the given field specs.
+func protoEntity(t *testing.T, specs ...string) EntitySpec {
+	t.Helper()
+	fields, err := parseFieldSpecs("Orders", specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newEntitySpec("orders", fields)
+}
+
+func TestAssignProtoNumbers(t *testing.T) {
+	e := protoEntity(t, "total:decimal", "note:string")
+	first := e.protoNumbers()
+	if !reflect.DeepEqual(first.Fields, map[string]int{"total": 1, "note": 2}) || first.Reserved != nil {
+		t.Fatalf("numbers of a new entity = %+v, want the declaration order", first)
+	}
+
+	// note is removed and paid declared first: total keeps 1, paid gets 3 and 2 stays reserved.
+	e = protoEntity(t, "paid:bool", "total:decimal")
+	e.Proto = first
+	second := e.protoNumbers()
+	if !reflect.DeepEqual(second.Fields, map[string]int{"paid": 3, "total": 1}) || !reflect.DeepEqual(second.Reserved, map[int]string{2: "note"}) {
+		t.Fatalf("numbers after removing a field = %+v", second)
+	}
+	if got := e.ProtoReservedNumbers(protoNumberOffset); got != "5" {
+		t.Errorf("ProtoReservedNumbers(3) = %q, want 5", got)
+	}
+	if got := e.ProtoReservedNames(); got != `"note"` {
+		t.Errorf("ProtoReservedNames() = %s, want \"note\"", got)
+	}
+
+	// A field added again under a removed name gets a new number; only its old one is reserved.
+	e = protoEntity(t, "paid:bool", "total:decimal", "note:string")
+	e.Proto = second
+	third := e.protoNumbers()
+	if third.Fields["note"] != 4 || third.Reserved[2] != "note" {
+		t.Fatalf("numbers after adding a removed field back = %+v", third)
+	}
+	if got := e.ProtoReservedNames(); got != "" {
+		t.Errorf("ProtoReservedNames() = %s, want none since note is in use", got)
+	}
+}
+
+func TestRegenerateKeepsProtoNumbers(t *testing.T) {
+	chdir(t, t.TempDir())
+	m := NewManifest(defaultModulePath)
+	entry := newServiceEntry("orders", 8081, TemplateGeneric)
+	entry.API = APIGRPC
+	entry.Fields = []string{"total:decimal:required", "note:string"}
+	if err := m.AddService(entry); err != nil {
+		t.Fatal(err)
+	}
+	entities, err := serviceEntities(m.Service("orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := serviceGeneratorOptions(m, m.Service("orders"))
+	files, err := generateAll(
+		func() (generatedFiles, error) { return createSharedPkg(osFS{}, opts) },
+		func() (generatedFiles, error) {
+			return createMicroservice(osFS{}, opts, "orders", "8081", "templates/", entities)
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recordBaselines(files); err != nil {
+		t.Fatal(err)
+	}
+	recordProtoNumbers(m.Service("orders"), entities)
+	if err := SaveManifest(ManifestFile, m); err != nil {
+		t.Fatal(err)
+	}
+
+	// note is dropped from the schema and paid added before total.
+	updated := []EntitySpec{protoEntity(t, "paid:bool", "total:decimal:required")}
+	if _, err := regenerateService(m, m.Service("orders"), "orders.yaml", updated, false); err != nil {
+		t.Fatal(err)
+	}
+
+	proto, err := os.ReadFile(filepath.Join(servicesDir, "orders", "proto", "orders.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"string total = 4;", "bool paid = 6;", "reserved 5;", // Orders
+		"string total = 1;", "bool paid = 3;", "reserved 2;", // OrdersInput
+		`reserved "note";`,
+	} {
+		if !strings.Contains(string(proto), want) {
+			t.Errorf("orders.proto lacks %q:\n%s", want, proto)
+		}
+	}
+	saved, err := LoadManifest(ManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Service("orders").ProtoFields["orders"]; got.Fields["paid"] != 3 || got.Reserved[2] != "note" {
+		t.Errorf("recorded numbers = %+v", got)
+	}
+}
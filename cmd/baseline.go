@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The .gores/ cache keeps the pristine rendering of every generated file, so 'gores upgrade'
+// can three-way merge newer templates with the edits made since. Commit it with the project.
+const (
+	goresDir          = ".gores"
+	baselineIndexFile = ".gores/baseline.yaml"
+	baselineFilesDir  = ".gores/baseline"
+)
+
+// baselineEntry describes the recorded rendering of one generated file.
+type baselineEntry struct {
+	Template     string `yaml:"template"`
+	SHA256       string `yaml:"sha256"`
+	GoresVersion string `yaml:"gores_version,omitempty"`
+}
+
+// baselineIndex is the typed representation of .gores/baseline.yaml, keyed by the
+// slash-separated path of each generated file.
+type baselineIndex struct {
+	Files map[string]baselineEntry `yaml:"files"`
+}
+
+func baselineKey(file string) string {
+	return filepath.ToSlash(filepath.Clean(file))
+}
+
+func baselineContentPath(key string) string {
+	return filepath.Join(filepath.FromSlash(baselineFilesDir), filepath.FromSlash(key))
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func loadBaselineIndex() (*baselineIndex, error) {
+	idx := &baselineIndex{Files: map[string]baselineEntry{}}
+	content, err := os.ReadFile(filepath.FromSlash(baselineIndexFile))
+	if os.IsNotExist(err) {
+		return idx, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", baselineIndexFile, err)
+	}
+	if err := yaml.Unmarshal(content, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", baselineIndexFile, err)
+	}
+	if idx.Files == nil {
+		idx.Files = map[string]baselineEntry{}
+	}
+	return idx, nil
+}
+
+// updateBaselines loads the baseline index under the project lock, applies fn and saves it.
+func updateBaselines(fn func(idx *baselineIndex) error) error {
+	return withProjectLock(".", func() error {
+		idx, err := loadBaselineIndex()
+		if err != nil {
+			return err
+		}
+		if err := fn(idx); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.FromSlash(goresDir), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create %s: %w", goresDir, err)
+		}
+		content, err := yaml.Marshal(idx)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", baselineIndexFile, err)
+		}
+		header := []byte("# Pristine renderings of generated files, used by 'gores upgrade'. Do not edit.\n")
+		return writeFileAtomic(filepath.FromSlash(baselineIndexFile), append(header, content...), 0644)
+	})
+}
+
+// putBaseline stores content as the baseline of key. The caller holds the index lock.
+func putBaseline(idx *baselineIndex, key, template string, content []byte) error {
+	path := baselineContentPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := writeFileAtomic(path, content, 0644); err != nil {
+		return err
+	}
+	idx.Files[key] = baselineEntry{Template: template, SHA256: contentHash(content), GoresVersion: Version}
+	return nil
+}
+
+// dropBaseline removes the baseline of key. The caller holds the index lock.
+func dropBaseline(idx *baselineIndex, key string) {
+	delete(idx.Files, key)
+	os.Remove(baselineContentPath(key))
+}
+
+// recordBaselines stores the current on-disk content of freshly generated files as their
+// baseline.
+func recordBaselines(files generatedFiles) error {
+	return updateBaselines(func(idx *baselineIndex) error {
+		for file, template := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read generated file %s: %w", file, err)
+			}
+			if err := putBaseline(idx, baselineKey(file), template, content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readBaseline returns the recorded baseline of file. A baseline whose content no longer
+// matches its recorded hash is treated as missing.
+func readBaseline(idx *baselineIndex, file string) ([]byte, bool) {
+	key := baselineKey(file)
+	entry, ok := idx.Files[key]
+	if !ok {
+		return nil, false
+	}
+	content, err := os.ReadFile(baselineContentPath(key))
+	if err != nil || contentHash(content) != entry.SHA256 {
+		return nil, false
+	}
+	return content, true
+}
+
+// dropServiceBaselines forgets the baselines of a service's files and of its files in the
+// shared module, such as its entities and typed client.
+func dropServiceBaselines(serviceName, template string, sharedFiles []string) error {
+	if _, err := os.Stat(filepath.FromSlash(baselineIndexFile)); os.IsNotExist(err) {
+		return nil
+	}
+	prefix := baselineKey(filepath.Join(servicesDir, serviceName)) + "/"
+	shared := map[string]bool{}
+	for _, file := range sharedFiles {
+		shared[baselineKey(file)] = true
+	}
+	return updateBaselines(func(idx *baselineIndex) error {
+		for key := range idx.Files {
+			if strings.HasPrefix(key, prefix) || (shared[key] && template != TemplateAuth) {
+				dropBaseline(idx, key)
+			}
+		}
+		os.RemoveAll(baselineContentPath(strings.TrimSuffix(prefix, "/")))
+		return nil
+	})
+}
+
+// renameServiceBaselines moves the baselines of a renamed service to their new paths and
+// applies the same rewrite the rename applied to the files themselves. Documents the rename
+// rendered again get that rendering as their baseline.
+func renameServiceBaselines(plan *renamePlan, oldName, newName, template string) error {
+	if _, err := os.Stat(filepath.FromSlash(baselineIndexFile)); os.IsNotExist(err) {
+		return nil
+	}
+	oldPrefix := baselineKey(filepath.Join(servicesDir, oldName)) + "/"
+	newPrefix := baselineKey(filepath.Join(servicesDir, newName)) + "/"
+	oldEntity := baselineKey(entityFilePath(oldName, template))
+	newEntity := baselineKey(entityFilePath(newName, template))
+	movedClient := map[string]string{}
+	newClientFiles := clientFiles(newName)
+	for i, file := range clientFiles(oldName) {
+		movedClient[baselineKey(file)] = baselineKey(newClientFiles[i])
+	}
+
+	return updateBaselines(func(idx *baselineIndex) error {
+		keys := make([]string, 0, len(idx.Files))
+		for key := range idx.Files {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var newKey string
+			switch {
+			case strings.HasPrefix(key, oldPrefix):
+				newKey = newPrefix + strings.TrimPrefix(key, oldPrefix)
+			case key == oldEntity && template != TemplateAuth:
+				newKey = newEntity
+			case movedClient[key] != "":
+				newKey = movedClient[key]
+			default:
+				continue
+			}
+
+			entry := idx.Files[key]
+			content, ok := readBaseline(idx, key)
+			dropBaseline(idx, key)
+			if !ok {
+				continue
+			}
+			updated, rendered := plan.baselines[key]
+			if !rendered {
+				var err error
+				if updated, err = plan.rewriteContent(key, content); err != nil {
+					// A baseline that cannot be rewritten is dropped; upgrade then skips the file.
+					fmt.Fprintf(os.Stderr, "Warning: dropped the upgrade baseline of %s: %v\n", key, err)
+					continue
+				}
+			}
+			if err := putBaseline(idx, newKey, entry.Template, updated); err != nil {
+				return err
+			}
+			idx.Files[newKey] = baselineEntry{Template: entry.Template, SHA256: contentHash(updated), GoresVersion: entry.GoresVersion}
+		}
+		os.RemoveAll(baselineContentPath(strings.TrimSuffix(oldPrefix, "/")))
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serviceBaselineKeys lists the baseline keys of a generic service's own and shared files.
+func serviceBaselineKeys(name string) []string {
+	keys := []string{baselineKey(entityFilePath(name, TemplateGeneric))}
+	for _, file := range clientFiles(name) {
+		keys = append(keys, baselineKey(file))
+	}
+	return keys
+}
+
+func TestRecordBaselines(t *testing.T) {
+	newServicesProject(t, "orders")
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range append(serviceBaselineKeys("orders"), "services/orders/internal/controller.go", "pkg/go.mod") {
+		if _, ok := idx.Files[key]; !ok {
+			t.Errorf("no baseline recorded for %s", key)
+		}
+	}
+	for key, entry := range idx.Files {
+		content, err := os.ReadFile(filepath.FromSlash(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if baseline, ok := readBaseline(idx, key); !ok || string(baseline) != string(content) {
+			t.Errorf("baseline of %s does not match the generated file", key)
+		}
+		if entry.Template == "" || entry.GoresVersion != Version {
+			t.Errorf("baseline entry of %s = %+v", key, entry)
+		}
+	}
+
+	// A baseline edited by hand no longer matches its hash and is ignored.
+	key := "services/orders/internal/controller.go"
+	if err := os.WriteFile(baselineContentPath(key), []byte("package internal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readBaseline(idx, key); ok {
+		t.Error("a baseline not matching its hash was read")
+	}
+}
+
+func TestRenameServiceBaselines(t *testing.T) {
+	newServicesProject(t, "orders")
+	runRename(t, "orders", "billing")
+
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key := range idx.Files {
+		if strings.HasPrefix(key, "services/orders/") {
+			t.Errorf("the baseline of %s was kept", key)
+		}
+	}
+	for _, key := range serviceBaselineKeys("orders") {
+		if _, ok := idx.Files[key]; ok {
+			t.Errorf("the baseline of %s was kept", key)
+		}
+	}
+	if _, err := os.Stat(baselineContentPath("services/orders")); !os.IsNotExist(err) {
+		t.Errorf("the baselines of orders were left in %s (err: %v)", goresDir, err)
+	}
+
+	// The untouched files were rewritten like their baselines, so both still agree.
+	renamed := append(serviceBaselineKeys("billing"), "services/billing/internal/controller.go", "services/billing/go.mod")
+	for _, key := range renamed {
+		content, err := os.ReadFile(filepath.FromSlash(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if baseline, ok := readBaseline(idx, key); !ok || string(baseline) != string(content) {
+			t.Errorf("baseline of %s does not match the renamed file", key)
+		}
+	}
+}
+
+func TestDropServiceBaselines(t *testing.T) {
+	newServicesProject(t, "orders", "billing")
+	if err := runRemove(t, "orders", false); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range append(serviceBaselineKeys("orders"), "services/orders/internal/controller.go") {
+		if _, ok := idx.Files[key]; ok {
+			t.Errorf("the baseline of %s was kept", key)
+		}
+		if _, err := os.Stat(baselineContentPath(key)); !os.IsNotExist(err) {
+			t.Errorf("the baseline content of %s was kept (err: %v)", key, err)
+		}
+	}
+	// The other service keeps its baselines.
+	for _, key := range append(serviceBaselineKeys("billing"), "services/billing/internal/controller.go") {
+		if _, ok := readBaseline(idx, key); !ok {
+			t.Errorf("the baseline of %s was dropped", key)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// clientsPkgFile is the runtime of the typed service clients: URL discovery, credentials,
+// timeouts and retries. It is rendered from clientsPkgTemplate.
+var clientsPkgFile = filepath.Join("pkg", "clients", "clients.go")
+
+const clientsPkgTemplate = "templates/clients.tmpl"
+
+// clientDir returns the package of the typed client generated into the shared module for
+// an HTTP service, e.g. pkg/clients/orders.
+func clientDir(serviceName string) string {
+	return filepath.Join("pkg", "clients", serviceName)
+}
+
+// clientFiles returns the files of a service's typed client.
+func clientFiles(serviceName string) []string {
+	dir := clientDir(serviceName)
+	return []string{filepath.Join(dir, "client.go"), filepath.Join(dir, "client_test.go")}
+}
+
+// clientPackageName is the Go package of a service's typed client, e.g. "orderitems".
+// Names that are Go keywords get a "client" suffix, e.g. "typeclient".
+func clientPackageName(serviceName string) string {
+	pkg := goPackageName(serviceName)
+	if token.IsKeyword(pkg) {
+		pkg += "client"
+	}
+	return pkg
+}
+
+// clientURLEnv is the environment variable overriding the URL a service's typed client
+// calls, e.g. "ORDER_ITEMS_URL".
+func clientURLEnv(serviceName string) string {
+	return strings.ToUpper(snakeCase(serviceName)) + "_URL"
+}
+
+// ClientPackage is the Go package of the service's typed client.
+func (d TemplateData) ClientPackage() string {
+	return clientPackageName(d.Name)
+}
+
+// ClientURLEnv is the environment variable overriding the URL the service's client calls.
+func (d TemplateData) ClientURLEnv() string {
+	return clientURLEnv(d.Name)
+}
+
+// ExposedEntities returns the entities the service serves routes for.
+func (d TemplateData) ExposedEntities() []EntitySpec {
+	var entities []EntitySpec
+	for _, e := range d.Entities {
+		if e.Expose {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+// ClientUsesEntities reports whether the typed client returns any entity, which every
+// exposed operation but delete does.
+func (d TemplateData) ClientUsesEntities() bool {
+	for _, e := range d.ExposedEntities() {
+		for _, op := range e.Operations {
+			if op != OperationDelete {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ClientRequestHasFieldKind reports whether a request body of the typed client holds a
+// field of the given kind, so the client imports its package.
+func (d TemplateData) ClientRequestHasFieldKind(kind string) bool {
+	for _, e := range d.ExposedEntities() {
+		if (e.Exposes("create") || e.Exposes("update")) && e.HasFieldKind(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// addClientTemplates adds the typed client of an HTTP service to the templates rendered
+// for it, along with the runtime it needs.
+func addClientTemplates(fsys projectFS, opts generatorOptions, name string, templates map[string]string, files generatedFiles) error {
+	dir := clientDir(name)
+	if err := fsys.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create folder %s: %w", dir, err)
+	}
+	clientFiles := clientFiles(name)
+	templates["templates/client.tmpl"] = clientFiles[0]
+	templates["templates/client_test.tmpl"] = clientFiles[1]
+
+	runtimeFiles, err := ensureClientsPkg(fsys, opts)
+	for file, tmplPath := range runtimeFiles {
+		files[file] = tmplPath
+	}
+	return err
+}
+
+// ensureClientsPkg adds the runtime of the typed clients to the shared pkg module of a
+// project created before it existed, leaving an existing one alone.
+func ensureClientsPkg(fsys projectFS, opts generatorOptions) (generatedFiles, error) {
+	files := generatedFiles{}
+	if _, err := fsys.Stat(filepath.Join("pkg", "go.mod")); os.IsNotExist(err) {
+		return files, nil // Without a shared module there is nothing to extend.
+	}
+	if _, err := fsys.Stat(clientsPkgFile); err == nil {
+		return files, nil
+	}
+	if err := fsys.MkdirAll(filepath.Dir(clientsPkgFile), os.ModePerm); err != nil {
+		return files, fmt.Errorf("failed to create %s folder: %w", filepath.ToSlash(filepath.Dir(clientsPkgFile)), err)
+	}
+	if err := writeTemplate(fsys, clientsPkgTemplate, clientsPkgFile, newTemplateData(opts, "", "")); err != nil {
+		return files, err
+	}
+	files[clientsPkgFile] = clientsPkgTemplate
+	reportf(fsys, "Generated: %s\n", clientsPkgFile)
+	return files, nil
+}
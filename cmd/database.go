@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// Database backends a service can persist its entities with.
+const (
+	DatabasePostgres = "postgres"
+	DatabaseMySQL    = "mysql"
+	DatabaseSQLite   = "sqlite"
+	DatabaseMongo    = "mongo"
+	DatabaseNone     = "none" // Entities are kept in memory by the service itself
+)
+
+// databaseNames lists the accepted --db values.
+var databaseNames = []string{DatabasePostgres, DatabaseMySQL, DatabaseSQLite, DatabaseMongo, DatabaseNone}
+
+// ID strategies of MongoDB services, chosen with --id-strategy.
+const (
+	IDStrategyObjectID = "objectid" // _id holds an ObjectID; the API shows its hex form
+	IDStrategyUUID     = "uuid"     // _id holds a random UUID string, as in the SQL databases
+)
+
+// databaseBackend describes what the shared pkg module needs for one database.
+type databaseBackend struct {
+	templates []pkgTemplate    // Files rendered into pkg/ for the backend
+	requires  []module.Version // Driver modules required by pkg/go.mod
+	envKeys   []string         // Environment variables read by the connection package
+}
+
+// databaseBackends maps every persistent database to its connection package. The
+// PostgreSQL backend also carries the migration runner, which only speaks PostgreSQL.
+var databaseBackends = map[string]databaseBackend{
+	DatabasePostgres: {
+		templates: []pkgTemplate{
+			{"templates/database_connection.tmpl", filepath.Join("pkg", "database", "postgres", "connection.go")},
+			{"templates/migrate.tmpl", filepath.Join("pkg", "database", "migrate", "migrate.go")},
+			{"templates/migrate_inspect.tmpl", filepath.Join("pkg", "database", "migrate", "inspect.go")},
+			{"templates/migrate_main.tmpl", filepath.Join("pkg", "cmd", "migrate", "main.go")},
+		},
+		requires: []module.Version{{Path: "gorm.io/driver/postgres", Version: "v1.6.0"}},
+		envKeys:  []string{"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB", "POSTGRES_SSLMODE"},
+	},
+	DatabaseMySQL: {
+		templates: []pkgTemplate{
+			{"templates/database_mysql.tmpl", filepath.Join("pkg", "database", "mysql", "connection.go")},
+		},
+		requires: []module.Version{{Path: "gorm.io/driver/mysql", Version: "v1.5.7"}},
+		envKeys:  []string{"MYSQL_HOST", "MYSQL_PORT", "MYSQL_USER", "MYSQL_PASSWORD", "MYSQL_DB"},
+	},
+	DatabaseSQLite: {
+		templates: []pkgTemplate{
+			{"templates/database_sqlite.tmpl", filepath.Join("pkg", "database", "sqlite", "connection.go")},
+		},
+		// A pure Go SQLite driver, so services still build with CGO_ENABLED=0.
+		requires: []module.Version{{Path: "github.com/glebarez/sqlite", Version: "v1.11.0"}},
+		envKeys:  []string{"SQLITE_PATH"},
+	},
+	DatabaseMongo: {
+		templates: []pkgTemplate{
+			{"templates/database_mongo.tmpl", filepath.Join("pkg", "database", "mongo", "connection.go")},
+		},
+		// The connection registers a BSON codec for decimal fields.
+		requires: []module.Version{
+			{Path: "github.com/shopspring/decimal", Version: "v1.4.0"},
+			{Path: "go.mongodb.org/mongo-driver", Version: "v1.17.6"},
+		},
+		envKeys: []string{"MONGO_URI", "MONGO_DB"},
+	},
+}
+
+// sqlDatabase reports whether a database is accessed through GORM, and so supports
+// relations between entities.
+func sqlDatabase(name string) bool {
+	return name == DatabasePostgres || name == DatabaseMySQL || name == DatabaseSQLite
+}
+
+// checkDatabase validates a --db value.
+func checkDatabase(name string) error {
+	for _, db := range databaseNames {
+		if name == db {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown database '%s': use one of %s", name, strings.Join(databaseNames, ", "))
+}
+
+// checkServiceDatabase fails if the entities need more than the service's database
+// offers: only SQL databases resolve relations.
+func checkServiceDatabase(database string, entities []EntitySpec) error {
+	if sqlDatabase(database) {
+		return nil
+	}
+	for _, e := range entities {
+		if len(e.Relations) > 0 {
+			return fmt.Errorf("entity '%s' declares relations, which services generated with --db %s do not support", e.Name, database)
+		}
+	}
+	return nil
+}
+
+// checkIDStrategy validates an --id-strategy value for a service on the given database.
+func checkIDStrategy(strategy, database string) error {
+	if database != DatabaseMongo {
+		return fmt.Errorf("--id-strategy only applies to services generated with --db mongo")
+	}
+	if strategy != IDStrategyObjectID && strategy != IDStrategyUUID {
+		return fmt.Errorf("unknown ID strategy '%s': use %s or %s", strategy, IDStrategyObjectID, IDStrategyUUID)
+	}
+	return nil
+}
+
+// pkgTemplates lists the templated files of the shared pkg module for a project using
+// the given HTTP framework and databases.
+func pkgTemplates(framework string, databases []string) []pkgTemplate {
+	templates := append([]pkgTemplate(nil), sharedPkgTemplates...)
+	templates = append(templates, pkgTemplate{frameworkTemplate(framework, "middleware.tmpl"), middlewarePkgFile})
+	for _, db := range databases {
+		templates = append(templates, databaseBackends[db].templates...)
+	}
+	return templates
+}
+
+// ensureDatabasePkg adds the connection package of a service's database to the shared pkg
+// module when the project did not use that database yet, leaving existing files alone.
+func ensureDatabasePkg(fsys projectFS, opts generatorOptions) (generatedFiles, error) {
+	files := generatedFiles{}
+	data := newTemplateData(opts, "", "")
+	data.Databases = []string{data.Database}
+	backend, ok := databaseBackends[data.Database]
+	if !ok {
+		return files, nil // No connection package, e.g. for in-memory services.
+	}
+	if _, err := fsys.Stat(filepath.Join("pkg", "go.mod")); os.IsNotExist(err) {
+		return files, nil // Without a shared module there is nothing to extend.
+	}
+
+	for _, f := range backend.templates {
+		if _, err := fsys.Stat(f.output); err == nil {
+			continue
+		}
+		if err := fsys.MkdirAll(filepath.Dir(f.output), os.ModePerm); err != nil {
+			return files, fmt.Errorf("failed to create %s folder: %w", filepath.ToSlash(filepath.Dir(f.output)), err)
+		}
+		if err := writeTemplate(fsys, f.template, f.output, data); err != nil {
+			return files, err
+		}
+		files[f.output] = f.template
+		reportf(fsys, "Generated: %s\n", f.output)
+	}
+	for _, r := range backend.requires {
+		if err := requirePkgDependency(fsys, r.Path, r.Version); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
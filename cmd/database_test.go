@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifestDatabases(t *testing.T) {
+	m := NewManifest("")
+	m.Services = []ServiceEntry{
+		{Name: authServiceName, Template: TemplateAuth},
+		{Name: "orders", Template: TemplateGeneric, Database: DatabaseMySQL},
+		{Name: "carts", Template: TemplateGeneric, Database: DatabaseNone},
+		{Name: "stock", Template: TemplateGeneric, Database: DatabaseMySQL},
+	}
+	if got, want := m.Databases(), []string{DatabasePostgres, DatabaseMySQL}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Databases() = %v, want %v", got, want)
+	}
+	if got := m.ServiceDatabase(m.Service(authServiceName)); got != DatabasePostgres {
+		t.Errorf("the auth service uses %q, want the project default", got)
+	}
+
+	m.Settings.Database = DatabaseSQLite
+	if got, want := m.Databases(), []string{DatabaseSQLite, DatabaseMySQL}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Databases() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckServiceDatabase(t *testing.T) {
+	if err := checkDatabase("oracle"); err == nil {
+		t.Error("an unknown database was accepted")
+	}
+
+	orders := newEntitySpec("orders", nil)
+	orders.Relations, _ = parseRelationSpecs("Orders", []string{"customer:belongs-to:user"})
+	if err := checkServiceDatabase(DatabaseSQLite, []EntitySpec{orders}); err != nil {
+		t.Errorf("relations were rejected for a SQLite service: %v", err)
+	}
+	if err := checkServiceDatabase(DatabaseNone, []EntitySpec{orders}); err == nil || !strings.Contains(err.Error(), "--db none") {
+		t.Errorf("relations of an in-memory service gave error %v", err)
+	}
+	if err := checkServiceDatabase(DatabaseMongo, []EntitySpec{orders}); err == nil || !strings.Contains(err.Error(), "--db mongo") {
+		t.Errorf("relations of a MongoDB service gave error %v", err)
+	}
+}
+
+func TestCheckIDStrategy(t *testing.T) {
+	for _, strategy := range []string{IDStrategyObjectID, IDStrategyUUID} {
+		if err := checkIDStrategy(strategy, DatabaseMongo); err != nil {
+			t.Errorf("%s was rejected for a MongoDB service: %v", strategy, err)
+		}
+	}
+	if err := checkIDStrategy("serial", DatabaseMongo); err == nil {
+		t.Error("an unknown ID strategy was accepted")
+	}
+	if err := checkIDStrategy(IDStrategyUUID, DatabasePostgres); err == nil {
+		t.Error("an ID strategy was accepted for a PostgreSQL service")
+	}
+
+	m := NewManifest("")
+	m.Services = []ServiceEntry{{Name: "reviews", Template: TemplateGeneric, Database: DatabaseMongo}}
+	if got := newTemplateData(serviceGeneratorOptions(m, m.Service("reviews")), "reviews", "8081").IDStrategy; got != IDStrategyObjectID {
+		t.Errorf("a MongoDB service without --id-strategy uses %q, want %q", got, IDStrategyObjectID)
+	}
+}
+
+func TestGormTagPerDatabase(t *testing.T) {
+	fields, err := parseFieldSpecs("Orders", []string{"email:string:required:unique", "customerId:uuid", "total:decimal", "meta:json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		DatabasePostgres: {"column:email;not null;uniqueIndex", "column:customer_id;type:uuid", "column:total;type:numeric(20,4)", "column:meta;type:jsonb"},
+		DatabaseMySQL:    {"column:email;type:varchar(255);not null;uniqueIndex", "column:customer_id;type:char(36)", "column:total;type:decimal(20,4)", "column:meta;type:json"},
+		DatabaseSQLite:   {"column:email;not null;uniqueIndex", "column:customer_id;type:text", "column:total;type:text", "column:meta;type:text"},
+	}
+	for db, tags := range want {
+		for i, f := range fields {
+			if got := f.GormTag(db); got != tags[i] {
+				t.Errorf("%s: GormTag of %s = %q, want %q", db, f.Name, got, tags[i])
+			}
+		}
+	}
+}
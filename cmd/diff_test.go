@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newText := "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- a/x
++++ b/x
+@@ -1,6 +1,6 @@
+ a
+ b
+-c
++C
+ d
+ e
+ f
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := unifiedDiff("a/x", "b/x", []byte(oldText), []byte(newText)); got != want {
+		t.Fatalf("unifiedDiff mismatch:\n%s\nwant:\n%s", got, want)
+	}
+	if got := unifiedDiff("a/x", "b/x", []byte(oldText), []byte(oldText)); got != "" {
+		t.Fatalf("expected no diff for equal input, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffNewFileAndMissingNewline(t *testing.T) {
+	got := unifiedDiff("/dev/null", "b/x", nil, []byte("one\ntwo"))
+	want := "--- /dev/null\n+++ b/x\n@@ -0,0 +1,2 @@\n+one\n+two\n\\ No newline at end of file\n"
+	if got != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestMemFSDoesNotTouchDisk(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	mem := newMemFS()
+	if _, err := createMicroservice(mem, generatorOptions{Module: defaultModulePath}, "orders", "8081", "templates/", nil); err != nil {
+		t.Fatalf("createMicroservice: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("dry run wrote %d entries to disk", len(entries))
+	}
+
+	main := filepath.Join(servicesDir, "orders", "cmd", "main.go")
+	content, err := mem.ReadFile(main)
+	if err != nil || !strings.Contains(string(content), "RegisterOrdersRoutes") {
+		t.Fatalf("memFS lost %s: %v", main, err)
+	}
+	if fi, err := mem.Stat(filepath.Join(servicesDir, "orders")); err != nil || !fi.IsDir() {
+		t.Fatalf("memFS does not report the service directory: %v", err)
+	}
+}
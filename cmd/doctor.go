@@ -0,0 +1,577 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	envFile    = ".env"
+	minPort    = 1024
+	pkgReplace = "../../pkg"
+)
+
+// requiredEnvKeys are the environment variables read by the generated services and shared
+// pkg, besides those of the project's database connections.
+var requiredEnvKeys = []string{
+	"JWT_SECRET",
+	"API_KEY",
+}
+
+var doctorFix bool
+
+// finding is a single problem reported by 'gores doctor'.
+type finding struct {
+	check   string       // Short category, e.g. "ports"
+	message string       // What is wrong
+	hint    string       // How to fix it by hand
+	fix     func() error // Mechanical fix applied with --fix; nil if manual action is needed
+}
+
+// doctorCmd is the Cobra command that cross-checks the monorepo for inconsistencies.
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the monorepo for inconsistencies",
+	Long: "Cross-checks gores.yaml against the services/ directory and reports orphaned entity files, duplicate or " +
+		"out-of-range ports, service go.mod files missing the 'replace <module>/pkg => ../../pkg' directive, go.work " +
+		"entries that are missing or stale, .env keys the generated code needs, and Dockerfiles whose build paths do not exist. Use --fix to repair the mechanical " +
+		"problems. Exits non-zero when problems remain, for use in CI.",
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true, // Execute prints the summary error once.
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --- Prerequisite Check ---
+		manifest, err := loadProjectManifest()
+		if err != nil {
+			return err
+		}
+		// --- End Prerequisite Check ---
+
+		findings, err := runDoctorChecks(manifest)
+		if err != nil {
+			return err
+		}
+		if len(findings) == 0 {
+			fmt.Println("No problems found. ✨")
+			return nil
+		}
+
+		remaining := 0
+		for _, f := range findings {
+			fmt.Printf("[%s] %s\n", f.check, f.message)
+			if doctorFix && f.fix != nil {
+				if err := f.fix(); err != nil {
+					fmt.Printf("    fix failed: %v\n", err)
+					remaining++
+				} else {
+					fmt.Println("    fixed.")
+				}
+				continue
+			}
+			remaining++
+			if f.fix != nil {
+				fmt.Printf("    -> %s (or run 'gores doctor --fix')\n", f.hint)
+			} else {
+				fmt.Printf("    -> %s\n", f.hint)
+			}
+		}
+
+		if remaining > 0 {
+			return fmt.Errorf("doctor found %d problem(s) that need attention", remaining)
+		}
+		fmt.Printf("All %d problem(s) fixed.\n", len(findings))
+		return nil
+	},
+}
+
+// runDoctorChecks runs every consistency check against the project in the current directory.
+func runDoctorChecks(m *Manifest) ([]finding, error) {
+	dirs, err := serviceDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	findings = append(findings, checkManifestAgainstDirs(m, dirs)...)
+	findings = append(findings, checkPorts(m)...)
+	findings = append(findings, checkOrphanedEntities(m, dirs)...)
+	pkgModule := m.Module + "/pkg"
+	replaceRequired := projectGeneratorOptions(m).Replace
+	for _, dir := range dirs {
+		goModFindings, err := checkPkgReplace(dir, pkgModule, replaceRequired)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, goModFindings...)
+		findings = append(findings, checkDockerfile(dir)...)
+	}
+	workspaceFindings, err := checkWorkspace(dirs)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, workspaceFindings...)
+	findings = append(findings, checkEnvKeys(m)...)
+	return findings, nil
+}
+
+// serviceDirs returns the names of the directories under services/.
+func serviceDirs() ([]string, error) {
+	entries, err := os.ReadDir(servicesDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read '%s/' directory: %w", servicesDir, err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs, nil
+}
+
+func checkManifestAgainstDirs(m *Manifest, dirs []string) []finding {
+	var findings []finding
+	for _, s := range m.Services {
+		name := s.Name
+		if !containsString(dirs, name) {
+			findings = append(findings, finding{
+				check:   "services",
+				message: fmt.Sprintf("service '%s' is registered in %s but %s does not exist", name, ManifestFile, filepath.Join(servicesDir, name)),
+				hint:    fmt.Sprintf("run 'gores remove %s --yes' to unregister it", name),
+				fix:     func() error { return ReleaseService(ManifestFile, name) },
+			})
+		}
+	}
+	for _, dir := range dirs {
+		name := dir
+		if m.Service(name) == nil {
+			template := TemplateGeneric
+			if name == authServiceName {
+				template = TemplateAuth
+			}
+			findings = append(findings, finding{
+				check:   "services",
+				message: fmt.Sprintf("%s exists but is not registered in %s", filepath.Join(servicesDir, name), ManifestFile),
+				hint:    "register it with a free port, or delete the directory",
+				fix: func() error {
+					entry, err := ReserveService(ManifestFile, name, 0, template)
+					if err == nil {
+						fmt.Printf("    registered '%s' on port %d.\n", name, entry.Port)
+					}
+					return err
+				},
+			})
+		}
+	}
+	return findings
+}
+
+func checkPorts(m *Manifest) []finding {
+	var findings []finding
+	owners := map[int][]string{}
+	for _, s := range m.Services {
+		owners[s.Port] = append(owners[s.Port], s.Name)
+		if s.Port < minPort || s.Port > maxPort {
+			name, port := s.Name, s.Port
+			findings = append(findings, finding{
+				check:   "ports",
+				message: fmt.Sprintf("service '%s' uses out-of-range port %d (allowed: %d-%d)", name, port, minPort, maxPort),
+				hint:    "assign a port in range in gores.yaml and the service's Dockerfile",
+				fix:     func() error { return reassignPort(name) },
+			})
+		}
+	}
+
+	ports := make([]int, 0, len(owners))
+	for port := range owners {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		names := owners[port]
+		if len(names) < 2 {
+			continue
+		}
+		// Keep the first owner; move every other service to a fresh port.
+		for _, name := range names[1:] {
+			name, first := name, names[0]
+			findings = append(findings, finding{
+				check:   "ports",
+				message: fmt.Sprintf("port %d is assigned to both '%s' and '%s'", port, first, name),
+				hint:    fmt.Sprintf("assign a different port to '%s' in gores.yaml and its Dockerfile", name),
+				fix:     func() error { return reassignPort(name) },
+			})
+		}
+	}
+	return findings
+}
+
+// reassignPort moves a service to the next available port in the manifest and its Dockerfile.
+func reassignPort(serviceName string) error {
+	var oldPort, newPort int
+	_, err := UpdateManifest(ManifestFile, func(m *Manifest) error {
+		s := m.Service(serviceName)
+		if s == nil {
+			return fmt.Errorf("service '%s' is not registered", serviceName)
+		}
+		oldPort = s.Port
+		s.Port = -1 // Exclude the old port from the availability check below.
+		port, err := AllocatePort(m)
+		if err != nil {
+			return err
+		}
+		s.Port, newPort = port, port
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dockerfilePath := filepath.Join(servicesDir, serviceName, "Dockerfile")
+	if content, err := os.ReadFile(dockerfilePath); err == nil {
+		portLine := regexp.MustCompile(`(?m)^(EXPOSE |ENV PORT=)` + strconv.Itoa(oldPort) + `\s*$`)
+		updated := portLine.ReplaceAll(content, []byte("${1}"+strconv.Itoa(newPort)))
+		if err := writeFileAtomic(dockerfilePath, updated, 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("    moved '%s' from port %d to %d.\n", serviceName, oldPort, newPort)
+	return nil
+}
+
+func checkOrphanedEntities(m *Manifest, dirs []string) []finding {
+	entitiesDir := filepath.Join("pkg", "entities")
+	entries, err := os.ReadDir(entitiesDir)
+	if err != nil {
+		return nil
+	}
+
+	owned := map[string]bool{}
+	for i := range m.Services {
+		for _, file := range serviceEntityFiles(&m.Services[i]) {
+			owned[file] = true
+		}
+	}
+	for _, dir := range dirs {
+		template := TemplateGeneric
+		if dir == authServiceName {
+			template = TemplateAuth
+		}
+		owned[entityFilePath(dir, template)] = true
+	}
+
+	var findings []finding
+	for _, entry := range entries {
+		file := filepath.Join(entitiesDir, entry.Name())
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".entity.go") || owned[file] {
+			continue
+		}
+		findings = append(findings, finding{
+			check:   "entities",
+			message: fmt.Sprintf("%s does not belong to any service", file),
+			hint:    "delete it if it is left over from a removed service, or move shared types to another file",
+		})
+	}
+	return findings
+}
+
+// checkPkgReplace checks that a service's go.mod points the shared pkg module at the local
+// pkg/ directory. The directive is required when the project uses replace directives, and
+// whenever go.mod requires the pkg module, which go.work alone cannot resolve. Services
+// generated before pkg had a full module path use the bare "pkg".
+func checkPkgReplace(serviceName, pkgModule string, required bool) ([]finding, error) {
+	goModPath := filepath.Join(servicesDir, serviceName, "go.mod")
+	content, err := os.ReadFile(goModPath)
+	if os.IsNotExist(err) {
+		return []finding{{
+			check:   "go.mod",
+			message: fmt.Sprintf("service '%s' has no go.mod", serviceName),
+			hint:    fmt.Sprintf("run 'go mod init' in %s", filepath.Join(servicesDir, serviceName)),
+		}}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", goModPath, err)
+	}
+
+	modFile, err := modfile.Parse(goModPath, content, nil)
+	if err != nil {
+		return []finding{{
+			check:   "go.mod",
+			message: fmt.Sprintf("%s cannot be parsed: %v", goModPath, err),
+			hint:    "fix the syntax error by hand",
+		}}, nil
+	}
+	for _, r := range modFile.Replace {
+		if (r.Old.Path == pkgModule || r.Old.Path == "pkg") && path.Clean(r.New.Path) == pkgReplace {
+			return nil, nil
+		}
+	}
+	requiresPkg := false
+	for _, r := range modFile.Require {
+		if r.Mod.Path == pkgModule || r.Mod.Path == "pkg" {
+			requiresPkg = true
+		}
+	}
+	if !required && !requiresPkg {
+		return nil, nil // Resolved through go.work.
+	}
+
+	message := fmt.Sprintf("%s lacks the 'replace %s => %s' directive", goModPath, pkgModule, pkgReplace)
+	if !required {
+		message = fmt.Sprintf("%s requires %s without the 'replace %s => %s' directive", goModPath, pkgModule, pkgModule, pkgReplace)
+	}
+	return []finding{{
+		check:   "go.mod",
+		message: message,
+		hint:    fmt.Sprintf("add 'replace %s => %s' to %s", pkgModule, pkgReplace, goModPath),
+		fix: func() error {
+			if !requiresPkg {
+				if err := modFile.AddRequire(pkgModule, "v0.0.0"); err != nil {
+					return err
+				}
+			}
+			if err := modFile.AddReplace(pkgModule, "", pkgReplace, ""); err != nil {
+				return err
+			}
+			formatted, err := modFile.Format()
+			if err != nil {
+				return err
+			}
+			return writeFileAtomic(goModPath, formatted, 0644)
+		},
+	}}, nil
+}
+
+// checkWorkspace checks that go.work, if the project has one, uses pkg and every service
+// module and nothing that no longer exists.
+func checkWorkspace(dirs []string) ([]finding, error) {
+	content, err := os.ReadFile(goWorkFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", goWorkFile, err)
+	}
+	work, err := modfile.ParseWork(goWorkFile, content, nil)
+	if err != nil {
+		return []finding{{
+			check:   "go.work",
+			message: fmt.Sprintf("%s cannot be parsed: %v", goWorkFile, err),
+			hint:    "fix the syntax error by hand",
+		}}, nil
+	}
+
+	used := map[string]bool{}
+	var findings []finding
+	for _, use := range work.Use {
+		dir := use.Path
+		used[filepath.Clean(dir)] = true
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			findings = append(findings, finding{
+				check:   "go.work",
+				message: fmt.Sprintf("%s uses %s, which does not exist", goWorkFile, dir),
+				hint:    fmt.Sprintf("remove 'use %s' from %s", dir, goWorkFile),
+				fix: func() error {
+					_, err := dropWorkspaceUse(dir)
+					return err
+				},
+			})
+		}
+	}
+
+	expected := []string{pkgModuleDir}
+	for _, name := range dirs {
+		expected = append(expected, serviceModuleDir(name))
+	}
+	for _, dir := range expected {
+		dir := dir
+		if used[filepath.Clean(dir)] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
+			continue // Reported by the go.mod check.
+		}
+		findings = append(findings, finding{
+			check:   "go.work",
+			message: fmt.Sprintf("%s does not use %s", goWorkFile, dir),
+			hint:    fmt.Sprintf("add 'use %s' to %s", dir, goWorkFile),
+			fix: func() error {
+				_, err := syncWorkspace(osFS{}, []string{dir}, false)
+				return err
+			},
+		})
+	}
+	return findings, nil
+}
+
+var goBuildTarget = regexp.MustCompile(`go build\b.*\s(\.\S*)\s*$`)
+
+func checkDockerfile(serviceName string) []finding {
+	dockerfilePath := filepath.Join(servicesDir, serviceName, "Dockerfile")
+	content, err := os.ReadFile(dockerfilePath)
+	if os.IsNotExist(err) {
+		return []finding{{
+			check:   "docker",
+			message: fmt.Sprintf("service '%s' has no Dockerfile", serviceName),
+			hint:    "regenerate the service or add a Dockerfile by hand",
+		}}
+	} else if err != nil {
+		return nil
+	}
+
+	var findings []finding
+	workdir := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "FROM":
+			workdir = ""
+		case "WORKDIR":
+			if len(fields) > 1 {
+				workdir = strings.TrimPrefix(strings.TrimPrefix(fields[1], "/app"), "/")
+			}
+		case "COPY":
+			if len(fields) < 3 || strings.HasPrefix(fields[1], "--from") {
+				continue
+			}
+			src := fields[1]
+			if src == envFile {
+				continue // Reported by the .env check.
+			}
+			if _, err := os.Stat(filepath.FromSlash(src)); os.IsNotExist(err) {
+				findings = append(findings, finding{
+					check:   "docker",
+					message: fmt.Sprintf("%s copies '%s', which does not exist in the project root build context", dockerfilePath, src),
+					hint:    "fix the COPY source path",
+				})
+			}
+		case "RUN":
+			match := goBuildTarget.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			target := match[1]
+			buildPath := filepath.Join(filepath.FromSlash(workdir), filepath.FromSlash(target))
+			if _, err := os.Stat(buildPath); err == nil {
+				continue
+			}
+			f := finding{
+				check:   "docker",
+				message: fmt.Sprintf("%s builds '%s', but %s does not exist", dockerfilePath, target, buildPath),
+				hint:    "point 'go build' at the service's main package",
+			}
+			for _, candidate := range []string{"./cmd", "./src/cmd"} {
+				if candidate == target {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(filepath.FromSlash(workdir), filepath.FromSlash(candidate))); err == nil {
+					oldLine, newLine := line, strings.Replace(line, " "+target, " "+candidate, 1)
+					f.hint = fmt.Sprintf("build '%s' instead", candidate)
+					f.fix = func() error {
+						current, err := os.ReadFile(dockerfilePath)
+						if err != nil {
+							return err
+						}
+						updated := strings.Replace(string(current), oldLine, newLine, 1)
+						return writeFileAtomic(dockerfilePath, []byte(updated), 0644)
+					}
+					break
+				}
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+func checkEnvKeys(m *Manifest) []finding {
+	present, err := readEnvKeys(envFile)
+	if err != nil && !os.IsNotExist(err) {
+		return []finding{{
+			check:   "env",
+			message: fmt.Sprintf("failed to read %s: %v", envFile, err),
+			hint:    "make sure the file is readable",
+		}}
+	}
+
+	keys := append([]string(nil), requiredEnvKeys...)
+	for _, db := range m.Databases() {
+		keys = append(keys, databaseBackends[db].envKeys...)
+	}
+	var missing []string
+	for _, key := range keys {
+		if !present[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("%s is missing keys used by the generated code: %s", envFile, strings.Join(missing, ", "))
+	if os.IsNotExist(err) {
+		message = fmt.Sprintf("%s does not exist; the generated code needs %s", envFile, strings.Join(missing, ", "))
+	}
+	return []finding{{
+		check:   "env",
+		message: message,
+		hint:    fmt.Sprintf("add the missing keys to %s", envFile),
+		fix: func() error {
+			f, err := os.OpenFile(envFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := f.WriteString("\n# Added by 'gores doctor --fix'; fill in real values.\n"); err != nil {
+				return err
+			}
+			for _, key := range missing {
+				if _, err := fmt.Fprintf(f, "%s=\n", key); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}}
+}
+
+// readEnvKeys returns the set of keys defined in a dotenv file.
+func readEnvKeys(file string) (map[string]bool, error) {
+	keys := map[string]bool{}
+	f, err := os.Open(file)
+	if err != nil {
+		return keys, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		if key, _, ok := strings.Cut(line, "="); ok {
+			keys[strings.TrimSpace(key)] = true
+		}
+	}
+	return keys, scanner.Err()
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Apply fixes for mechanical problems")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,498 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+// newDoctorProject switches to a temporary project holding a manifest with the given
+// services, which may share ports, and the given files. A path ending in "/" is created
+// as an empty directory.
+func newDoctorProject(t *testing.T, services []ServiceEntry, files map[string]string) *Manifest {
+	t.Helper()
+	chdir(t, t.TempDir())
+	m := NewManifest(defaultModulePath)
+	m.Settings.BasePort = 23000
+	m.Settings.NextPort = 23000
+	m.Services = services
+	if err := SaveManifest(ManifestFile, m); err != nil {
+		t.Fatalf("SaveManifest: %v", err)
+	}
+	for path, content := range files {
+		path = filepath.FromSlash(path)
+		if strings.HasSuffix(path, string(filepath.Separator)) {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+// applyFixes runs the fix of every finding, as 'gores doctor --fix' does. It fails the
+// test if a finding has no fix.
+func applyFixes(t *testing.T, findings []finding) {
+	t.Helper()
+	for _, f := range findings {
+		if f.fix == nil {
+			t.Fatalf("[%s] %s has no fix", f.check, f.message)
+		}
+		if err := f.fix(); err != nil {
+			t.Fatalf("fixing [%s] %s: %v", f.check, f.message, err)
+		}
+	}
+}
+
+// loadTestManifest loads the manifest of the current project.
+func loadTestManifest(t *testing.T) *Manifest {
+	t.Helper()
+	m, err := LoadManifest(ManifestFile)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	return m
+}
+
+// serviceNames lists the names of the services registered in m, in manifest order.
+func serviceNames(m *Manifest) []string {
+	var names []string
+	for _, s := range m.Services {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestCheckManifestAgainstDirs(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		services []string
+		dirs     []string
+		want     int      // Findings before --fix
+		after    []string // Services registered after --fix
+	}{
+		{"in sync", []string{"orders"}, []string{"orders"}, 0, []string{"orders"}},
+		{"missing directory", []string{"orders", "billing"}, []string{"orders"}, 1, []string{"orders"}},
+		{"unregistered directory", []string{"orders"}, []string{"billing", "orders"}, 1, []string{"orders", "billing"}},
+		{"both", []string{"billing"}, []string{"orders"}, 2, []string{"orders"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var services []ServiceEntry
+			for i, name := range tt.services {
+				services = append(services, newServiceEntry(name, 8081+i, TemplateGeneric))
+			}
+			files := map[string]string{}
+			for _, dir := range tt.dirs {
+				files["services/"+dir+"/"] = ""
+			}
+			m := newDoctorProject(t, services, files)
+
+			dirs, err := serviceDirs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings := checkManifestAgainstDirs(m, dirs)
+			if len(findings) != tt.want {
+				t.Fatalf("got %d findings, want %d: %+v", len(findings), tt.want, findings)
+			}
+			applyFixes(t, findings)
+
+			m = loadTestManifest(t)
+			if got := serviceNames(m); !reflect.DeepEqual(got, tt.after) {
+				t.Errorf("services after --fix = %v, want %v", got, tt.after)
+			}
+			if findings := checkManifestAgainstDirs(m, dirs); len(findings) != 0 {
+				t.Errorf("findings remain after --fix: %+v", findings)
+			}
+		})
+	}
+}
+
+func TestCheckManifestRegistersAuthDirectory(t *testing.T) {
+	newDoctorProject(t, nil, map[string]string{"services/" + authServiceName + "/": ""})
+
+	dirs, err := serviceDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyFixes(t, checkManifestAgainstDirs(loadTestManifest(t), dirs))
+
+	s := loadTestManifest(t).Service(authServiceName)
+	if s == nil || s.Template != TemplateAuth {
+		t.Errorf("%s registered as %+v, want template %q", authServiceName, s, TemplateAuth)
+	}
+}
+
+func TestCheckPorts(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		ports map[string]int
+		want  int      // Findings before --fix
+		kept  []string // Services that keep their port
+	}{
+		{"distinct", map[string]int{"orders": 8081, "billing": 8082}, 0, []string{"orders", "billing"}},
+		{"duplicate", map[string]int{"orders": 8081, "billing": 8081}, 1, []string{"orders"}},
+		{"three way", map[string]int{"orders": 8081, "billing": 8081, "stock": 8081}, 2, []string{"orders"}},
+		{"below range", map[string]int{"orders": 80, "billing": 8082}, 1, []string{"billing"}},
+		{"above range", map[string]int{"orders": 8081, "billing": 70000}, 1, []string{"orders"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// Manifest order decides which owner of a shared port is kept.
+			var services []ServiceEntry
+			files := map[string]string{}
+			for _, name := range []string{"orders", "billing", "stock"} {
+				port, ok := tt.ports[name]
+				if !ok {
+					continue
+				}
+				services = append(services, newServiceEntry(name, port, TemplateGeneric))
+				files["services/"+name+"/Dockerfile"] = dockerfileWithPort(port)
+			}
+			m := newDoctorProject(t, services, files)
+
+			findings := checkPorts(m)
+			if len(findings) != tt.want {
+				t.Fatalf("got %d findings, want %d: %+v", len(findings), tt.want, findings)
+			}
+			applyFixes(t, findings)
+
+			m = loadTestManifest(t)
+			if findings := checkPorts(m); len(findings) != 0 {
+				t.Errorf("findings remain after --fix: %+v", findings)
+			}
+			for _, s := range m.Services {
+				if containsString(tt.kept, s.Name) && s.Port != tt.ports[s.Name] {
+					t.Errorf("'%s' moved from port %d to %d, want it kept", s.Name, tt.ports[s.Name], s.Port)
+				}
+				dockerfile, err := os.ReadFile(filepath.Join(servicesDir, s.Name, "Dockerfile"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := dockerfileWithPort(s.Port); string(dockerfile) != want {
+					t.Errorf("Dockerfile of '%s' =\n%s\nwant:\n%s", s.Name, dockerfile, want)
+				}
+			}
+		})
+	}
+}
+
+// dockerfileWithPort is a Dockerfile exposing port the way the generated ones do.
+func dockerfileWithPort(port int) string {
+	return "FROM alpine\nENV PORT=" + strconv.Itoa(port) + "\nEXPOSE " + strconv.Itoa(port) + "\nCMD [\"/server\"]\n"
+}
+
+func TestCheckOrphanedEntities(t *testing.T) {
+	orders := newServiceEntry("orders", 8081, TemplateGeneric)
+	orders.Entities = []SchemaEntity{{Name: "order"}, {Name: "line_item"}}
+	m := newDoctorProject(t, []ServiceEntry{orders}, map[string]string{
+		"services/billing/":                 "",
+		"services/" + authServiceName + "/": "",
+		"pkg/entities/orders.entity.go":     "package entities\n",
+		"pkg/entities/line_item.entity.go":  "package entities\n",
+		"pkg/entities/billing.entity.go":    "package entities\n",
+		"pkg/entities/user.entity.go":       "package entities\n",
+		"pkg/entities/invoices.entity.go":   "package entities\n",
+		"pkg/entities/helpers.go":           "package entities\n",
+	})
+
+	dirs, err := serviceDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := checkOrphanedEntities(m, dirs)
+	if len(findings) != 1 || !strings.Contains(findings[0].message, "invoices.entity.go") {
+		t.Fatalf("got findings %+v, want one for invoices.entity.go", findings)
+	}
+	if findings[0].fix != nil {
+		t.Errorf("an orphaned entity has a fix; it needs a decision by hand")
+	}
+}
+
+func TestCheckPkgReplace(t *testing.T) {
+	const pkgModule = defaultModulePath + "/pkg"
+	for _, tt := range []struct {
+		name     string
+		goMod    string // Empty for no go.mod
+		required bool   // The project uses replace directives
+		want     bool   // A finding is reported
+		fix      bool   // The finding has a fix
+	}{
+		{"no go.mod", "", false, true, false},
+		{"unparsable", "module orders\nrequire (\n", false, true, false},
+		{"replaced", "module orders\n\nrequire gores/pkg v0.0.0\n\nreplace gores/pkg => ../../pkg\n", true, false, false},
+		{"legacy bare pkg", "module orders\n\nrequire pkg v0.0.0\n\nreplace pkg => ../../pkg/\n", true, false, false},
+		{"resolved by go.work", "module orders\n\ngo 1.24\n", false, false, false},
+		{"required by the project", "module orders\n\ngo 1.24\n", true, true, true},
+		{"required by go.mod", "module orders\n\nrequire gores/pkg v0.0.0\n", false, true, true},
+		{"replaced elsewhere", "module orders\n\nrequire gores/pkg v0.0.0\n\nreplace gores/pkg => ../pkg\n", false, true, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"services/orders/": ""}
+			if tt.goMod != "" {
+				files["services/orders/go.mod"] = tt.goMod
+			}
+			newDoctorProject(t, nil, files)
+
+			findings, err := checkPkgReplace("orders", pkgModule, tt.required)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(findings) > 0; got != tt.want {
+				t.Fatalf("got findings %+v, want a finding: %v", findings, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			if got := findings[0].fix != nil; got != tt.fix {
+				t.Fatalf("finding %q has a fix: %v, want %v", findings[0].message, got, tt.fix)
+			}
+			if !tt.fix {
+				return
+			}
+			applyFixes(t, findings)
+
+			if findings, err := checkPkgReplace("orders", pkgModule, tt.required); err != nil || len(findings) != 0 {
+				t.Errorf("findings remain after --fix: %+v (err: %v)", findings, err)
+			}
+			goModPath := filepath.Join(servicesDir, "orders", "go.mod")
+			content, err := os.ReadFile(goModPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			modFile, err := modfile.Parse(goModPath, content, nil)
+			if err != nil {
+				t.Fatalf("go.mod no longer parses after --fix: %v\n%s", err, content)
+			}
+			requires := 0
+			for _, r := range modFile.Require {
+				if r.Mod.Path == pkgModule {
+					requires++
+				}
+			}
+			if requires != 1 {
+				t.Errorf("go.mod requires %s %d times after --fix, want once:\n%s", pkgModule, requires, content)
+			}
+		})
+	}
+}
+
+func TestCheckDockerfile(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		dockerfile string // Empty for no Dockerfile
+		want       int    // Findings before --fix
+		fixed      string // Dockerfile after --fix; empty if a finding has no fix
+	}{
+		{
+			name: "no Dockerfile",
+			want: 1,
+		},
+		{
+			name: "valid",
+			dockerfile: "FROM golang AS build\nWORKDIR /app\nCOPY pkg ./pkg\nCOPY services/orders ./services/orders\n" +
+				"COPY .env .env\nWORKDIR /app/services/orders\nRUN go build -o /server ./cmd\n" +
+				"FROM alpine\nCOPY --from=build /server /server\n",
+		},
+		{
+			name:       "missing COPY source",
+			dockerfile: "FROM golang\nCOPY pkg ./pkg\nCOPY services/billing ./services/billing\nCOPY shared/ ./shared/\n",
+			want:       2,
+		},
+		{
+			name:       "moved main package",
+			dockerfile: "FROM golang\nWORKDIR /app/services/orders\nRUN CGO_ENABLED=0 go build -o /server ./src/cmd\n",
+			want:       1,
+			fixed:      "FROM golang\nWORKDIR /app/services/orders\nRUN CGO_ENABLED=0 go build -o /server ./cmd\n",
+		},
+		{
+			name:       "no main package",
+			dockerfile: "FROM golang\nWORKDIR /app/services/billing\nRUN go build -o /server ./cmd\n",
+			want:       1,
+		},
+		{
+			// FROM starts a new stage, so the build path is relative to the project root again.
+			name:       "WORKDIR of an earlier stage",
+			dockerfile: "FROM golang\nWORKDIR /app/services/orders\nFROM golang\nRUN go build -o /server ./cmd\n",
+			want:       1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{
+				"pkg/go.mod":                  "module gores/pkg\n",
+				"services/orders/cmd/main.go": "package main\n",
+				".env":                        "JWT_SECRET=\n",
+			}
+			if tt.dockerfile != "" {
+				files["services/orders/Dockerfile"] = tt.dockerfile
+			}
+			newDoctorProject(t, nil, files)
+
+			findings := checkDockerfile("orders")
+			if len(findings) != tt.want {
+				t.Fatalf("got %d findings, want %d: %+v", len(findings), tt.want, findings)
+			}
+			if tt.fixed == "" {
+				for _, f := range findings {
+					if f.fix != nil {
+						t.Errorf("finding %q has a fix, want none", f.message)
+					}
+				}
+				return
+			}
+			applyFixes(t, findings)
+
+			if findings := checkDockerfile("orders"); len(findings) != 0 {
+				t.Errorf("findings remain after --fix: %+v", findings)
+			}
+			content, err := os.ReadFile(filepath.Join(servicesDir, "orders", "Dockerfile"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.fixed {
+				t.Errorf("Dockerfile after --fix =\n%s\nwant:\n%s", content, tt.fixed)
+			}
+		})
+	}
+}
+
+func TestCheckEnvKeys(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		database  string            // Project default database
+		databases map[string]string // Database of each service, by name
+		env       string            // Content of .env; empty for no file
+		missing   []string          // Keys reported missing
+	}{
+		{
+			name:    "no .env",
+			missing: append(append([]string(nil), requiredEnvKeys...), databaseBackends[DatabasePostgres].envKeys...),
+		},
+		{
+			name:     "complete",
+			database: DatabaseSQLite,
+			env:      "# secrets\nJWT_SECRET=s3cret\nexport API_KEY=key\nSQLITE_PATH=data.db\n",
+		},
+		{
+			name:     "missing required key",
+			database: DatabaseSQLite,
+			env:      "JWT_SECRET=s3cret\nSQLITE_PATH=data.db\n",
+			missing:  []string{"API_KEY"},
+		},
+		{
+			name:      "database of one service",
+			database:  DatabaseSQLite,
+			databases: map[string]string{"orders": DatabaseMongo},
+			env:       "JWT_SECRET=s3cret\nAPI_KEY=key\nSQLITE_PATH=data.db\n",
+			missing:   databaseBackends[DatabaseMongo].envKeys,
+		},
+		{
+			name:      "service without a database",
+			database:  DatabaseSQLite,
+			databases: map[string]string{"orders": DatabaseNone},
+			env:       "JWT_SECRET=s3cret\nAPI_KEY=key\nSQLITE_PATH=data.db\n",
+		},
+		{
+			name:      "several databases",
+			database:  DatabaseMySQL,
+			databases: map[string]string{"orders": DatabasePostgres, "billing": DatabaseMySQL},
+			env:       "JWT_SECRET=s3cret\nAPI_KEY=key\nMYSQL_HOST=db\nPOSTGRES_HOST=db",
+			missing:   []string{"MYSQL_PORT", "MYSQL_USER", "MYSQL_PASSWORD", "MYSQL_DB", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB", "POSTGRES_SSLMODE"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var services []ServiceEntry
+			for _, name := range []string{"orders", "billing"} {
+				if db, ok := tt.databases[name]; ok {
+					s := newServiceEntry(name, 8081+len(services), TemplateGeneric)
+					s.Database = db
+					services = append(services, s)
+				}
+			}
+			files := map[string]string{}
+			if tt.env != "" {
+				files[envFile] = tt.env
+			}
+			m := newDoctorProject(t, services, files)
+			m.Settings.Database = tt.database
+
+			findings := checkEnvKeys(m)
+			if len(tt.missing) == 0 {
+				if len(findings) != 0 {
+					t.Fatalf("got findings %+v, want none", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("got %d findings, want 1: %+v", len(findings), findings)
+			}
+			want := ": " + strings.Join(tt.missing, ", ")
+			if tt.env == "" {
+				want = " needs " + strings.Join(tt.missing, ", ")
+			}
+			if !strings.HasSuffix(findings[0].message, want) {
+				t.Errorf("message = %q, want it to end in %q", findings[0].message, want)
+			}
+			applyFixes(t, findings)
+
+			if findings := checkEnvKeys(m); len(findings) != 0 {
+				t.Errorf("findings remain after --fix: %+v", findings)
+			}
+			content, err := os.ReadFile(envFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(content), tt.env) {
+				t.Errorf("--fix rewrote the existing keys of %s:\n%s", envFile, content)
+			}
+		})
+	}
+}
+
+func TestDoctorFixRepairsProject(t *testing.T) {
+	newOrdersProject(t)
+	// Break the project in every way --fix repairs.
+	if err := ReleaseService(ManifestFile, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dropWorkspaceUse(serviceModuleDir("orders")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(envFile, []byte("JWT_SECRET=s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := runDoctorChecks(loadTestManifest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := map[string]bool{}
+	for _, f := range findings {
+		checks[f.check] = true
+	}
+	for _, check := range []string{"services", "go.work", "env"} {
+		if !checks[check] {
+			t.Errorf("no [%s] finding in %+v", check, findings)
+		}
+	}
+
+	doctorFix = true
+	t.Cleanup(func() { doctorFix = false })
+	if err := doctorCmd.RunE(doctorCmd, nil); err != nil {
+		t.Fatalf("doctor --fix: %v", err)
+	}
+	if err := doctorCmd.RunE(doctorCmd, nil); err != nil {
+		t.Errorf("doctor after --fix: %v", err)
+	}
+}
@@ -0,0 +1,578 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Field kinds accepted in --field specs.
+const (
+	FieldString  = "string"
+	FieldInt     = "int"
+	FieldBool    = "bool"
+	FieldTime    = "time"
+	FieldUUID    = "uuid"
+	FieldDecimal = "decimal"
+	FieldJSON    = "json"
+	FieldEnum    = "enum"
+)
+
+var fieldKinds = []string{FieldString, FieldInt, FieldBool, FieldTime, FieldUUID, FieldDecimal, FieldJSON, FieldEnum}
+
+// Field modifiers accepted after the kind, e.g. "email:string:required:unique".
+const (
+	ModifierOptional = "optional" // Nullable column, pointer type in Go; "nullable" is an alias
+	ModifierRequired = "required" // NOT NULL column, rejected by request validation when missing
+	ModifierUnique   = "unique"   // Unique index
+	ModifierIndexed  = "indexed"  // Non-unique index; "index" is an alias
+)
+
+var (
+	fieldNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	enumValuePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+// reservedFieldNames are the columns every generated entity already has. SQL keywords such
+// as order or user are accepted: generated migrations quote every identifier.
+var reservedFieldNames = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+
+// EntityField is one typed field of a generated entity, parsed from a spec such as
+// "total:decimal:required" or "status:enum(pending,paid)".
+type EntityField struct {
+	Entity     string   // Go type name of the owning entity, e.g. "Orders"
+	Name       string   // Field name as given in the spec
+	Kind       string   // One of the Field* kinds
+	EnumValues []string // Allowed values of an enum field
+	Optional   bool
+	Required   bool
+	Unique     bool
+	Indexed    bool
+}
+
+// parseFieldSpecs parses the --field specs of a service whose entity type is entity.
+func parseFieldSpecs(entity string, specs []string) ([]EntityField, error) {
+	fields := make([]EntityField, 0, len(specs))
+	seen := map[string]string{}
+	for _, spec := range specs {
+		f, err := parseFieldSpec(entity, spec)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[f.GoName()]; ok {
+			return nil, fmt.Errorf("field '%s' clashes with field '%s'", f.Name, prev)
+		}
+		seen[f.GoName()] = f.Name
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func parseFieldSpec(entity, spec string) (EntityField, error) {
+	name, rest, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || rest == "" {
+		return EntityField{}, fmt.Errorf("invalid field '%s': expected name:type[:modifier...], e.g. total:decimal:required", spec)
+	}
+	f := EntityField{Entity: entity, Name: name}
+	if !fieldNamePattern.MatchString(name) {
+		return f, fmt.Errorf("invalid field name '%s': must start with a letter and contain only letters, digits or '_'", name)
+	}
+	if reservedFieldNames[f.Column()] {
+		return f, fmt.Errorf("field '%s' is reserved: every entity already has id, created_at and updated_at", name)
+	}
+
+	// The enum value list may contain ':' only inside its parentheses.
+	kind := rest
+	var modifiers []string
+	if end := strings.Index(rest, ")"); strings.HasPrefix(rest, FieldEnum+"(") && end > 0 {
+		kind = rest[:end+1]
+		if tail := rest[end+1:]; tail != "" {
+			if !strings.HasPrefix(tail, ":") {
+				return f, fmt.Errorf("invalid field '%s': unexpected '%s' after the enum values", spec, tail)
+			}
+			modifiers = strings.Split(tail[1:], ":")
+		}
+	} else if i := strings.Index(rest, ":"); i >= 0 {
+		kind, modifiers = rest[:i], strings.Split(rest[i+1:], ":")
+	}
+
+	if strings.HasPrefix(kind, FieldEnum+"(") && strings.HasSuffix(kind, ")") {
+		f.Kind = FieldEnum
+		seen := map[string]bool{}
+		for _, v := range strings.Split(kind[len(FieldEnum)+1:len(kind)-1], ",") {
+			v = strings.TrimSpace(v)
+			if !enumValuePattern.MatchString(v) {
+				return f, fmt.Errorf("invalid value '%s' for enum field '%s': use letters, digits, '-' and '_'", v, name)
+			}
+			if seen[toPascalCase(v)] {
+				return f, fmt.Errorf("enum field '%s' lists '%s' twice", name, v)
+			}
+			seen[toPascalCase(v)] = true
+			f.EnumValues = append(f.EnumValues, v)
+		}
+	} else if kind == FieldEnum {
+		return f, fmt.Errorf("enum field '%s' needs its values, e.g. %s:enum(pending,paid)", name, name)
+	} else if containsString(fieldKinds, kind) {
+		f.Kind = kind
+	} else {
+		return f, fmt.Errorf("unknown type '%s' for field '%s'; expected one of %s", kind, name, strings.Join(fieldKinds, ", "))
+	}
+
+	for _, m := range modifiers {
+		switch m {
+		case ModifierOptional, "nullable":
+			f.Optional = true
+		case ModifierRequired:
+			f.Required = true
+		case ModifierUnique:
+			f.Unique = true
+		case ModifierIndexed, "index":
+			f.Indexed = true
+		default:
+			return f, fmt.Errorf("unknown modifier '%s' for field '%s'; expected optional, required, unique or indexed", m, name)
+		}
+	}
+	if f.Optional && f.Required {
+		return f, fmt.Errorf("field '%s' cannot be both optional and required", name)
+	}
+	return f, nil
+}
+
+// String returns the canonical spec of the field, as recorded in gores.yaml.
+func (f EntityField) String() string {
+	kind := f.Kind
+	if f.Kind == FieldEnum {
+		kind = fmt.Sprintf("%s(%s)", FieldEnum, strings.Join(f.EnumValues, ","))
+	}
+	parts := []string{f.Name, kind}
+	for _, m := range []struct {
+		set  bool
+		name string
+	}{{f.Optional, ModifierOptional}, {f.Required, ModifierRequired}, {f.Unique, ModifierUnique}, {f.Indexed, ModifierIndexed}} {
+		if m.set {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// GoName is the exported Go name of the field, e.g. "CustomerEmail".
+func (f EntityField) GoName() string {
+	return toPascalCase(f.Name)
+}
+
+// Column is the snake_case column and JSON name of the field, e.g. "customer_email".
+func (f EntityField) Column() string {
+	return snakeCase(f.Name)
+}
+
+// snakeCase converts a camelCase, PascalCase or hyphenated name to snake_case.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(strings.ReplaceAll(name, "-", "_"))
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// EnumType is the name of the string type generated for an enum field, e.g. "OrdersStatus".
+func (f EntityField) EnumType() string {
+	return f.Entity + f.GoName()
+}
+
+// EnumConst is the name of the constant generated for one enum value, e.g. "OrdersStatusPaid".
+func (f EntityField) EnumConst(value string) string {
+	return f.EnumType() + toPascalCase(value)
+}
+
+// baseType is the Go type of a non-optional value of the field.
+func (f EntityField) baseType() string {
+	switch f.Kind {
+	case FieldInt:
+		return "int64"
+	case FieldBool:
+		return "bool"
+	case FieldTime:
+		return "time.Time"
+	case FieldDecimal:
+		return "decimal.Decimal"
+	case FieldJSON:
+		return "json.RawMessage"
+	case FieldEnum:
+		return f.EnumType()
+	default: // string, uuid
+		return "string"
+	}
+}
+
+// GoType is the Go type of the field in the entity struct. Optional fields are pointers,
+// except JSON, where nil already means NULL.
+func (f EntityField) GoType() string {
+	if f.Optional && f.Kind != FieldJSON {
+		return "*" + f.baseType()
+	}
+	return f.baseType()
+}
+
+// RequestPointer reports whether the request DTO holds the field as a pointer so that a
+// missing required value can be told apart from its zero value.
+func (f EntityField) RequestPointer() bool {
+	return f.Required && (f.Kind == FieldInt || f.Kind == FieldBool || f.Kind == FieldDecimal)
+}
+
+// RequestType is the Go type of the field in the request DTO, which lives outside the
+// entities package.
+func (f EntityField) RequestType() string {
+	t := f.GoType()
+	if f.Kind == FieldEnum {
+		t = strings.Replace(t, f.EnumType(), "entities."+f.EnumType(), 1)
+	}
+	if f.RequestPointer() {
+		return "*" + t
+	}
+	return t
+}
+
+// GormTag is the content of the field's gorm struct tag for the given database. Column
+// types are spelled out wherever gorm's default would lose precision or cannot be indexed.
+func (f EntityField) GormTag(database string) string {
+	settings := []string{"column:" + f.Column()}
+	switch database {
+	case DatabaseMySQL:
+		switch f.Kind {
+		case FieldUUID:
+			settings = append(settings, "type:char(36)")
+		case FieldDecimal:
+			settings = append(settings, "type:decimal(20,4)")
+		case FieldJSON:
+			settings = append(settings, "type:json")
+		case FieldEnum:
+			settings = append(settings, "type:varchar(255)")
+		case FieldString:
+			if f.Unique || f.Indexed {
+				settings = append(settings, "type:varchar(255)") // MySQL cannot index TEXT columns.
+			}
+		}
+	case DatabaseSQLite:
+		switch f.Kind {
+		case FieldUUID, FieldDecimal, FieldJSON, FieldEnum:
+			settings = append(settings, "type:text")
+		}
+	default:
+		switch f.Kind {
+		case FieldUUID:
+			settings = append(settings, "type:uuid")
+		case FieldDecimal:
+			settings = append(settings, "type:numeric(20,4)")
+		case FieldJSON:
+			settings = append(settings, "type:jsonb")
+		case FieldEnum:
+			settings = append(settings, "type:text")
+		}
+	}
+	if f.Required {
+		settings = append(settings, "not null")
+	}
+	if f.Unique {
+		settings = append(settings, "uniqueIndex")
+	} else if f.Indexed {
+		settings = append(settings, "index")
+	}
+	return strings.Join(settings, ";")
+}
+
+// SQLType is the PostgreSQL column type of the field, as created by migrations.
+func (f EntityField) SQLType() string {
+	switch f.Kind {
+	case FieldInt:
+		return "bigint"
+	case FieldBool:
+		return "boolean"
+	case FieldTime:
+		return "timestamptz"
+	case FieldUUID:
+		return "uuid"
+	case FieldDecimal:
+		return "numeric(20,4)"
+	case FieldJSON:
+		return "jsonb"
+	}
+	return "text"
+}
+
+// BSONTag is the content of the field's bson struct tag in MongoDB services.
+func (f EntityField) BSONTag() string {
+	if f.Optional {
+		return f.Column() + ",omitempty"
+	}
+	return f.Column()
+}
+
+// JSONTag is the content of the field's json struct tag.
+func (f EntityField) JSONTag() string {
+	if f.Optional {
+		return f.Column() + ",omitempty"
+	}
+	return f.Column()
+}
+
+// ProtoType is the protobuf type of the field in the messages of gRPC services. Decimals
+// and JSON documents travel as their text, enums as their value.
+func (f EntityField) ProtoType() string {
+	switch f.Kind {
+	case FieldInt:
+		return "int64"
+	case FieldBool:
+		return "bool"
+	case FieldTime:
+		return "google.protobuf.Timestamp"
+	}
+	return "string"
+}
+
+// ProtoOptional reports whether the field is declared optional in the entity's message,
+// which gives it a pointer type in Go. Timestamps are messages, so they always have
+// presence, and an empty string stands for a missing JSON document.
+func (f EntityField) ProtoOptional() bool {
+	return f.Optional && f.Kind != FieldTime && f.Kind != FieldJSON
+}
+
+// ProtoInputOptional reports whether the field is declared optional in the entity's input
+// message. Like the request DTO, it also needs presence for required fields whose zero
+// value is valid, so that a missing value can be rejected.
+func (f EntityField) ProtoInputOptional() bool {
+	return f.ProtoOptional() || f.RequestPointer()
+}
+
+// ProtoGoName is the Go name protoc-gen-go gives the field, e.g. "CustomerEmail".
+func (f EntityField) ProtoGoName() string {
+	return goCamelCase(f.Column())
+}
+
+// SampleValue is a Go expression of a valid non-optional value of the field, used by the
+// generated tests; enum values are qualified with the entities package.
+func (f EntityField) SampleValue() string {
+	switch f.Kind {
+	case FieldInt:
+		return "42"
+	case FieldBool:
+		return "true"
+	case FieldTime:
+		return "time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)"
+	case FieldUUID:
+		return `"` + sampleUUID + `"`
+	case FieldDecimal:
+		return `decimal.RequireFromString("19.99")`
+	case FieldJSON:
+		return "json.RawMessage(`" + sampleJSONObject + "`)"
+	case FieldEnum:
+		return "entities." + f.EnumConst(f.EnumValues[0])
+	}
+	return `"example"`
+}
+
+// SampleJSON is the JSON encoding of the field's SampleValue, as sent in request bodies.
+func (f EntityField) SampleJSON() string {
+	switch f.Kind {
+	case FieldInt, FieldBool:
+		return f.SampleValue()
+	case FieldTime:
+		return `"2024-01-02T15:04:05Z"`
+	case FieldUUID:
+		return `"` + sampleUUID + `"`
+	case FieldDecimal:
+		return `"19.99"`
+	case FieldJSON:
+		return sampleJSONObject
+	case FieldEnum:
+		return `"` + f.EnumValues[0] + `"`
+	}
+	return `"example"`
+}
+
+// ProtoSampleValue is a Go expression of the field's SampleValue in the entity's protobuf
+// input message, used by the generated gRPC tests; ptr comes from their helpers.
+func (f EntityField) ProtoSampleValue() string {
+	var v string
+	switch f.Kind {
+	case FieldTime:
+		return "timestamppb.New(" + f.SampleValue() + ")"
+	case FieldInt:
+		v = "int64(" + f.SampleValue() + ")"
+	case FieldBool, FieldUUID:
+		v = f.SampleValue()
+	default: // decimals, JSON documents and enums travel as text
+		v = f.SampleJSON()
+		if f.Kind == FieldJSON {
+			v = "`" + v + "`"
+		}
+	}
+	if f.ProtoInputOptional() {
+		return "ptr(" + v + ")"
+	}
+	return v
+}
+
+// GraphQLName is the name of the field in the schema of GraphQL services, e.g. "customerEmail".
+func (f EntityField) GraphQLName() string {
+	name := f.GoName()
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// GraphQLType is the GraphQL type of the field's values. GraphQL's Int is only 32 bits wide,
+// so integers use the services' Int64 scalar; decimals, JSON documents and enums travel as
+// their text, like in gRPC services, since enum values need not be GraphQL names.
+func (f EntityField) GraphQLType() string {
+	switch f.Kind {
+	case FieldInt:
+		return "Int64"
+	case FieldBool:
+		return "Boolean"
+	case FieldTime:
+		return "Time"
+	}
+	return "String"
+}
+
+// GraphQLDescription documents the values a String field accepts in the schema, if it
+// accepts less than any text.
+func (f EntityField) GraphQLDescription() string {
+	switch f.Kind {
+	case FieldDecimal:
+		return `A decimal number, e.g. \"19.99\".`
+	case FieldJSON:
+		return "A JSON document, as text."
+	case FieldUUID:
+		return "A UUID."
+	case FieldEnum:
+		return "One of: " + strings.Join(f.EnumValues, ", ") + "."
+	}
+	return ""
+}
+
+// GraphQLGoType is the Go type of the field's values in the resolvers of GraphQL services.
+func (f EntityField) GraphQLGoType() string {
+	switch f.Kind {
+	case FieldInt:
+		return "Int64"
+	case FieldBool:
+		return "bool"
+	case FieldTime:
+		return "graphql.Time"
+	}
+	return "string"
+}
+
+// GraphQLValue is a Go expression converting expr, a value of the field's non-optional
+// entity type, into its GraphQLGoType.
+func (f EntityField) GraphQLValue(expr string) string {
+	switch f.Kind {
+	case FieldInt:
+		return "Int64(" + expr + ")"
+	case FieldTime:
+		return "graphql.Time{Time: " + expr + "}"
+	case FieldDecimal:
+		return expr + ".String()"
+	case FieldJSON, FieldEnum:
+		return "string(" + expr + ")"
+	}
+	return expr
+}
+
+// GraphQLInputValue is a Go expression converting expr, a value of the field's GraphQLGoType,
+// into its non-optional entity type. Decimals and JSON documents are parsed by the
+// resolvers instead, since their text may be invalid.
+func (f EntityField) GraphQLInputValue(expr string) string {
+	switch f.Kind {
+	case FieldInt:
+		return "int64(" + expr + ")"
+	case FieldTime:
+		return expr + ".Time"
+	case FieldEnum:
+		return "entities." + f.EnumType() + "(" + expr + ")"
+	}
+	return expr
+}
+
+// GraphQLSampleJSON is the field's SampleJSON as a GraphQL variable, where JSON documents
+// are text.
+func (f EntityField) GraphQLSampleJSON() string {
+	if f.Kind == FieldJSON {
+		return strconv.Quote(sampleJSONObject)
+	}
+	return f.SampleJSON()
+}
+
+// OpenAPIType is the JSON Schema type of the field's values in the OpenAPI spec of HTTP
+// services. Decimals are encoded as strings by shopspring/decimal; JSON documents may be
+// any value, so they have no type.
+func (f EntityField) OpenAPIType() string {
+	switch f.Kind {
+	case FieldInt:
+		return "integer"
+	case FieldBool:
+		return "boolean"
+	case FieldJSON:
+		return ""
+	}
+	return "string"
+}
+
+// OpenAPIFormat is the format refining the field's OpenAPIType, if any, e.g. "date-time".
+func (f EntityField) OpenAPIFormat() string {
+	switch f.Kind {
+	case FieldInt:
+		return "int64"
+	case FieldTime:
+		return "date-time"
+	case FieldUUID:
+		return "uuid"
+	case FieldDecimal:
+		return "decimal"
+	}
+	return ""
+}
+
+// OpenAPIEnum is the YAML list of an enum field's values, quoted so that values such as
+// "true" or "1" stay strings.
+func (f EntityField) OpenAPIEnum() string {
+	values := make([]string, len(f.EnumValues))
+	for i, v := range f.EnumValues {
+		values[i] = strconv.Quote(v)
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// Values shared by SampleValue and SampleJSON.
+const (
+	sampleUUID       = "7d444840-9dc0-11d1-b245-5ffdce74fad2"
+	sampleJSONObject = `{"key":"value"}`
+)
+
+// fieldKindsUsed returns the set of kinds used by fields.
+func fieldKindsUsed(fields []EntityField) map[string]bool {
+	kinds := map[string]bool{}
+	for _, f := range fields {
+		kinds[f.Kind] = true
+	}
+	return kinds
+}
+
+// fieldSpecs returns the canonical specs of fields.
+func fieldSpecs(fields []EntityField) []string {
+	specs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		specs = append(specs, f.String())
+	}
+	return specs
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFieldSpec(t *testing.T) {
+	cases := []struct {
+		spec string
+		want EntityField
+	}{
+		{"total:decimal", EntityField{Entity: "Orders", Name: "total", Kind: FieldDecimal}},
+		{"email:string:required:unique", EntityField{Entity: "Orders", Name: "email", Kind: FieldString, Required: true, Unique: true}},
+		{"paidAt:time:nullable:index", EntityField{Entity: "Orders", Name: "paidAt", Kind: FieldTime, Optional: true, Indexed: true}},
+		{"status:enum(pending,paid)", EntityField{Entity: "Orders", Name: "status", Kind: FieldEnum, EnumValues: []string{"pending", "paid"}}},
+		{"status:enum(pending, paid):required", EntityField{Entity: "Orders", Name: "status", Kind: FieldEnum, EnumValues: []string{"pending", "paid"}, Required: true}},
+	}
+	for _, tc := range cases {
+		got, err := parseFieldSpec("Orders", tc.spec)
+		if err != nil {
+			t.Errorf("parseFieldSpec(%q): %v", tc.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseFieldSpec(%q) = %+v, want %+v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseFieldSpecErrors(t *testing.T) {
+	cases := map[string]string{
+		"total":                         "expected name:type",
+		"total:money":                   "unknown type 'money'",
+		"9lives:int":                    "invalid field name",
+		"id:uuid":                       "reserved",
+		"createdAt:time":                "reserved",
+		"status:enum":                   "needs its values",
+		"status:enum()":                 "invalid value ''",
+		"status:enum(a,a)":              "lists 'a' twice",
+		"status:enum(a,b)x":             "unexpected 'x'",
+		"note:string:sparkly":           "unknown modifier 'sparkly'",
+		"note:string:optional:required": "both optional and required",
+	}
+	for spec, want := range cases {
+		_, err := parseFieldSpec("Orders", spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseFieldSpec(%q) error = %v, want it to mention %q", spec, err, want)
+		}
+	}
+
+	// SQL keywords are valid field names, since migrations quote the columns.
+	for _, spec := range []string{"order:int", "user:string:unique", "group:string", "select:bool"} {
+		if _, err := parseFieldSpec("Orders", spec); err != nil {
+			t.Errorf("parseFieldSpec(%q) = %v", spec, err)
+		}
+	}
+
+	if _, err := parseFieldSpecs("Orders", []string{"customer_id:uuid", "customerId:uuid"}); err == nil {
+		t.Error("parseFieldSpecs accepted two fields with the same Go name")
+	}
+}
+
+func TestEntityFieldRendering(t *testing.T) {
+	fields, err := parseFieldSpecs("Orders", []string{
+		"customerId:uuid:required:indexed",
+		"total:decimal:required",
+		"note:string:optional",
+		"metadata:json:optional",
+		"status:enum(pending,paid):unique",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		got, want string
+	}{
+		{fields[0].GormTag(DatabasePostgres), "column:customer_id;type:uuid;not null;index"},
+		{fields[0].JSONTag(), "customer_id"},
+		{fields[1].GoType(), "decimal.Decimal"},
+		{fields[1].RequestType(), "*decimal.Decimal"},
+		{fields[2].GoType(), "*string"},
+		{fields[2].JSONTag(), "note,omitempty"},
+		{fields[3].GoType(), "json.RawMessage"},
+		{fields[4].GoType(), "OrdersStatus"},
+		{fields[4].RequestType(), "entities.OrdersStatus"},
+		{fields[4].EnumConst("paid"), "OrdersStatusPaid"},
+		{fields[4].GormTag(DatabasePostgres), "column:status;type:text;uniqueIndex"},
+		{strings.Join(fieldSpecs(fields), " "), "customerId:uuid:required:indexed total:decimal:required note:string:optional " +
+			"metadata:json:optional status:enum(pending,paid):unique"},
+	}
+	for i, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("case %d: got %q, want %q", i, tc.got, tc.want)
+		}
+	}
+}
+
+func TestEntitySampleJSON(t *testing.T) {
+	fields, err := parseFieldSpecs("Orders", []string{
+		"email:string:required", "note:string:optional", "quantity:int", "paid:bool", "paidAt:time",
+		"customerId:uuid", "total:decimal", "meta:json", "status:enum(pending,paid)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := newEntitySpec("orders", fields).SampleJSON()
+	want := `{"email":"example","quantity":42,"paid":true,"paid_at":"2024-01-02T15:04:05Z",` +
+		`"customer_id":"7d444840-9dc0-11d1-b245-5ffdce74fad2","total":"19.99","meta":{"key":"value"},"status":"pending"}`
+	if got != want {
+		t.Errorf("SampleJSON() = %s, want %s", got, want)
+	}
+	if !json.Valid([]byte(got)) {
+		t.Errorf("SampleJSON() is not valid JSON: %s", got)
+	}
+}
+
+func TestEntityFieldGraphQL(t *testing.T) {
+	fields, err := parseFieldSpecs("Orders", []string{
+		"customer_email:string:required", "quantity:int:optional", "paidAt:time", "meta:json",
+		"status:enum(pending,in-store)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		got, want string
+	}{
+		{fields[0].GraphQLName(), "customerEmail"},
+		{fields[0].GraphQLType(), "String"},
+		{fields[1].GraphQLType(), "Int64"},
+		{fields[1].GraphQLValue("*item.Quantity"), "Int64(*item.Quantity)"},
+		{fields[1].GraphQLInputValue("*in.Quantity"), "int64(*in.Quantity)"},
+		{fields[2].GraphQLName(), "paidAt"},
+		{fields[2].GraphQLGoType(), "graphql.Time"},
+		{fields[2].GraphQLInputValue("in.PaidAt"), "in.PaidAt.Time"},
+		{fields[3].GraphQLValue("item.Meta"), "string(item.Meta)"},
+		{fields[4].GraphQLType(), "String"},
+		{fields[4].GraphQLDescription(), "One of: pending, in-store."},
+		{fields[4].GraphQLInputValue("in.Status"), "entities.OrdersStatus(in.Status)"},
+		{newEntitySpec("orders", fields).GraphQLSampleInput(),
+			`{"customerEmail":"example","paidAt":"2024-01-02T15:04:05Z","meta":"{\"key\":\"value\"}","status":"pending"}`},
+	}
+	for i, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("case %d: got %q, want %q", i, tc.got, tc.want)
+		}
+	}
+}
+
+func TestEntityFieldOpenAPI(t *testing.T) {
+	fields, err := parseFieldSpecs("Orders", []string{
+		"quantity:int", "total:decimal", "meta:json", "placedAt:time", "status:enum(true,1,paid)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		got, want string
+	}{
+		{fields[0].OpenAPIType(), "integer"},
+		{fields[0].OpenAPIFormat(), "int64"},
+		{fields[1].OpenAPIType(), "string"},
+		{fields[1].OpenAPIFormat(), "decimal"},
+		{fields[2].OpenAPIType(), ""},
+		{fields[3].OpenAPIFormat(), "date-time"},
+		{fields[4].OpenAPIEnum(), `["true", "1", "paid"]`},
+	}
+	for i, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("case %d: got %q, want %q", i, tc.got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// HTTP frameworks a project's services are generated for.
+const (
+	FrameworkFiber   = "fiber"
+	FrameworkChi     = "chi"
+	FrameworkGin     = "gin"
+	FrameworkEcho    = "echo"
+	FrameworkNetHTTP = "net/http" // The standard library's http.ServeMux
+)
+
+// frameworkNames lists the accepted --framework values.
+var frameworkNames = []string{FrameworkFiber, FrameworkChi, FrameworkGin, FrameworkEcho, FrameworkNetHTTP}
+
+// frameworkTemplateDirs lists, most specific first, the directories the HTTP templates of
+// each framework are looked up in. Fiber's live at the root of templates/. chi routes
+// plain http.HandlerFuncs, so it only brings its own routers and shares everything else
+// with net/http; gin and echo bring their own routers, controllers and middleware, and
+// share main and the route tests with net/http, as their routers are http.Handlers too.
+var frameworkTemplateDirs = map[string][]string{
+	FrameworkFiber:   {"templates/"},
+	FrameworkChi:     {"templates/chi/", "templates/nethttp/"},
+	FrameworkGin:     {"templates/gin/", "templates/nethttp/"},
+	FrameworkEcho:    {"templates/echo/", "templates/nethttp/"},
+	FrameworkNetHTTP: {"templates/nethttp/"},
+}
+
+// checkFramework validates a --framework value.
+func checkFramework(name string) error {
+	for _, framework := range frameworkNames {
+		if name == framework {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown framework '%s': use one of %s", name, strings.Join(frameworkNames, ", "))
+}
+
+// frameworkTemplate returns the template the framework renders name from, e.g.
+// "templates/chi/main.tmpl" for "main.tmpl". Templates are searched in the framework's
+// directories in order; a name none of them provides resolves in the last one, so that
+// rendering it reports the missing template.
+func frameworkTemplate(framework, name string) string {
+	dirs, ok := frameworkTemplateDirs[framework]
+	if !ok {
+		dirs = frameworkTemplateDirs[FrameworkFiber]
+	}
+	for _, dir := range dirs {
+		if _, err := fs.Stat(templatesFS, dir+name); err == nil {
+			return dir + name
+		}
+	}
+	return dirs[len(dirs)-1] + name
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckFramework(t *testing.T) {
+	for _, framework := range frameworkNames {
+		if err := checkFramework(framework); err != nil {
+			t.Errorf("%s was rejected: %v", framework, err)
+		}
+	}
+	if err := checkFramework("martini"); err == nil {
+		t.Error("an unknown framework was accepted")
+	}
+}
+
+func TestFrameworkTemplate(t *testing.T) {
+	tests := []struct{ framework, name, want string }{
+		{FrameworkFiber, "router.tmpl", "templates/router.tmpl"},
+		{"", "router.tmpl", "templates/router.tmpl"},
+		{FrameworkChi, "router.tmpl", "templates/chi/router.tmpl"},
+		{FrameworkChi, "controller.tmpl", "templates/nethttp/controller.tmpl"},
+		{FrameworkGin, "auth/main.tmpl", "templates/nethttp/auth/main.tmpl"},
+		{FrameworkEcho, "middleware.tmpl", "templates/echo/middleware.tmpl"},
+		{FrameworkNetHTTP, "missing.tmpl", "templates/nethttp/missing.tmpl"},
+	}
+	for _, tt := range tests {
+		if got := frameworkTemplate(tt.framework, tt.name); got != tt.want {
+			t.Errorf("frameworkTemplate(%q, %q) = %q, want %q", tt.framework, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestManifestFramework(t *testing.T) {
+	m := NewManifest("")
+	if got := m.Framework(); got != FrameworkFiber {
+		t.Errorf("Framework() = %q, want the fiber default", got)
+	}
+	m.Settings.Framework = FrameworkChi
+	if got := m.Framework(); got != FrameworkChi {
+		t.Errorf("Framework() = %q, want %q", got, FrameworkChi)
+	}
+}
+
+func TestGenerateFrameworkMustMatchProject(t *testing.T) {
+	newServicesProject(t)
+	t.Cleanup(func() { generateFramework = "" })
+	for framework, want := range map[string]string{
+		"martini":    "invalid --framework",
+		FrameworkChi: "project uses framework 'fiber'",
+	} {
+		generateFramework = framework
+		if err := generateCmd.RunE(generateCmd, []string{"billing"}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("generate --framework %s = %v, want it to mention %q", framework, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(servicesDir, "billing")); !os.IsNotExist(err) {
+		t.Fatalf("a rejected --framework generated the service (err: %v)", err)
+	}
+
+	generateFramework = FrameworkFiber
+	if err := generateCmd.RunE(generateCmd, []string{"billing"}); err != nil {
+		t.Fatalf("generate --framework fiber in a Fiber project: %v", err)
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"embed"
 
 	"fmt"
-	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
-	"os/exec"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/module"
 )
 
 type CustomError struct {
@@ -24,67 +25,186 @@ func (e *CustomError) Error() string {
 //go:embed templates/*
 var templatesFS embed.FS
 
+const (
+	servicesDir     = "services" // Base directory for microservices
+	authServiceName = "auth-service"
+	authServicePort = 8080
+)
+
 // --- Prerequisite Check Function ---
-func checkInitPrerequisite() error {
-	const usedPortsFile = "used_ports.json"
-	_, err := os.Stat(usedPortsFile)
+
+// loadProjectManifest loads gores.yaml (migrating legacy port files if needed) and
+// fails if the current directory has not been initialized with 'gores init'.
+func loadProjectManifest() (*Manifest, error) {
+	m, err := LoadManifest(ManifestFile)
 	if os.IsNotExist(err) {
-		return fmt.Errorf("project not initialized. Please run 'gores init' first to set up the basic project structure and auth service.")
+		return nil, fmt.Errorf("project not initialized. Please run 'gores init' first to set up the basic project structure and auth service.")
 	} else if err != nil {
-		return fmt.Errorf("error checking project initialization status: %w", err)
+		return nil, fmt.Errorf("error checking project initialization status: %w", err)
+	}
+	return m, nil
+}
+
+// newServiceEntry builds the manifest entry for a service generated now by this gores version.
+func newServiceEntry(name string, port int, template string) ServiceEntry {
+	return ServiceEntry{
+		Name:         name,
+		Port:         port,
+		Template:     template,
+		GeneratedAt:  time.Now().UTC().Truncate(time.Second),
+		GoresVersion: Version,
 	}
-	return nil
 }
 
+var (
+	initModule        string
+	initReplace       bool
+	initDryRun        bool
+	initDiff          bool
+	initDatabase      string
+	initFramework     string
+	generateVerify    bool
+	generateDryRun    bool
+	generateDiff      bool
+	generateFields    []string
+	generateFrom      string
+	generateRelations []string
+	generateDatabase  string
+	generateIDs       string
+	generateAPI       string
+	generateFramework string
+)
+
 // --- Cobra Commands ---
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize the gores project with default pkg and auth-service",
-	Long:  "Creates the shared 'pkg' directory structure and generates the essential 'auth-service' by default.",
+	Long:  "Creates the shared 'pkg' directory structure, the gores.yaml project manifest and generates the essential 'auth-service' by default.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		const (
-			authServiceName       = "auth-service"
-			authServicePort       = "8080"
-			usedPortsFile         = "used_ports.json"
-			nextAvailablePortFile = "next_available_port.txt"
-		)
+		// With --dry-run or --diff every write goes to memory and is reported instead.
+		var fsys projectFS = osFS{}
+		var preview *memFS
+		if initDryRun || initDiff {
+			preview = newMemFS()
+			fsys = preview
+		}
+
+		// Settle the project module path first; every generated module path derives from it.
+		modulePath := defaultModulePath
+		if initModule != "" {
+			if err := module.CheckImportPath(initModule); err != nil {
+				return fmt.Errorf("invalid --module: %w", err)
+			}
+			modulePath = initModule
+		}
+		base, err := previewProjectManifest(modulePath)
+		if err != nil {
+			return err
+		}
+		if initModule != "" && base.Module != initModule {
+			return fmt.Errorf("project already uses module path '%s'; it cannot be changed with --module", base.Module)
+		}
+		modulePath = base.Module
+		database := base.DefaultDatabase()
+		if initDatabase != "" {
+			if err := checkDatabase(initDatabase); err != nil {
+				return fmt.Errorf("invalid --db: %w", err)
+			}
+			if !sqlDatabase(initDatabase) {
+				return fmt.Errorf("the auth service needs a SQL database; --db %s is only available to 'gores generate'", initDatabase)
+			}
+			if base.Service(authServiceName) != nil && initDatabase != database {
+				return fmt.Errorf("project already uses database '%s'; it cannot be changed with --db", database)
+			}
+			database = initDatabase
+		}
+		framework := base.Framework()
+		if initFramework != "" {
+			if err := checkFramework(initFramework); err != nil {
+				return fmt.Errorf("invalid --framework: %w", err)
+			}
+			if base.Service(authServiceName) != nil && initFramework != framework {
+				return fmt.Errorf("project already uses framework '%s'; it cannot be changed with --framework", framework)
+			}
+			framework = initFramework
+		}
+		opts := generatorOptions{Module: modulePath, Replace: base.Settings.ReplaceDirectives, Framework: framework, Database: database}
+		if cmd.Flags().Changed("replace-directives") {
+			opts.Replace = initReplace
+		}
 
-		fmt.Println("Initializing gores project...")
+		reportf(fsys, "Initializing gores project...\n")
 
-		if err := createSharedPkg(); err != nil {
+		files, err := createSharedPkg(fsys, opts)
+		if err != nil {
 			return fmt.Errorf("failed to create shared pkg: %w", err)
 		}
 
 		if ServiceExists(authServiceName) { // Call from port_management.go
-			fmt.Printf("Auth service '%s' already exists, skipping generation.\n", authServiceName)
-			if err := WriteUsedPortForService(8080, authServiceName, usedPortsFile); err != nil { // Call from port_management.go
-				return fmt.Errorf("failed to ensure auth service port is registered: %w", err)
-			}
+			reportf(fsys, "Auth service '%s' already exists, skipping generation.\n", authServiceName)
 		} else {
-			fmt.Printf("Generating default auth service '%s' on port %s...\n", authServiceName, authServicePort)
-			if err := createAuthMicroservice(authServiceName, authServicePort); err != nil {
+			reportf(fsys, "Generating default auth service '%s' on port %d...\n", authServiceName, authServicePort)
+			authFiles, err := createAuthMicroservice(fsys, opts, authServiceName, strconv.Itoa(authServicePort))
+			if err != nil {
 				return fmt.Errorf("failed to generate auth service: %w", err)
 			}
-			if err := WriteUsedPortForService(8080, authServiceName, usedPortsFile); err != nil {
-				return fmt.Errorf("failed to record auth service port: %w", err)
+			for file, tmpl := range authFiles {
+				files[file] = tmpl
 			}
 		}
-		currentNextPort := 8080 // This will be the base for comparison
-		portBytes, err := os.ReadFile(nextAvailablePortFile)
-		if err == nil {
-			p, convErr := strconv.Atoi(string(portBytes))
-			if convErr == nil {
-				currentNextPort = p
+
+		// go.work ties pkg and every service together for the go command and gopls.
+		workspaceDirs := []string{pkgModuleDir, serviceModuleDir(authServiceName)}
+		existing, err := serviceDirs() // Call from doctor.go
+		if err != nil {
+			return err
+		}
+		for _, name := range existing {
+			workspaceDirs = append(workspaceDirs, serviceModuleDir(name))
+		}
+		if changed, err := syncWorkspace(fsys, workspaceDirs, true); err != nil {
+			return err
+		} else if changed {
+			reportf(fsys, "Generated: %s\n", goWorkFile)
+		}
+
+		if preview != nil {
+			base.Settings.ReplaceDirectives = opts.Replace
+			if database != DatabasePostgres {
+				base.Settings.Database = database
+			}
+			if framework != FrameworkFiber {
+				base.Settings.Framework = framework
 			}
+			if err := registerAuthService(base); err != nil {
+				return err
+			}
+			if err := writeManifestPreview(preview, base); err != nil {
+				return err
+			}
+			preview.Report(initDiff)
+			return nil
 		}
 
-		if currentNextPort <= 8080 { // If the file isn't updated or points to 8080 or less
-			if err := os.WriteFile(nextAvailablePortFile, []byte(strconv.Itoa(8081)), 0644); err != nil {
-				return fmt.Errorf("failed to update next available port file: %w", err)
+		manifest, err := CreateOrUpdateManifest(ManifestFile, func(m *Manifest) error {
+			m.Module = modulePath
+			m.Settings.ReplaceDirectives = opts.Replace
+			if database != DatabasePostgres {
+				m.Settings.Database = database
 			}
-			fmt.Println("Next auto-assigned port will start from 8081.")
-		} else {
-			fmt.Printf("Next auto-assigned port already set to %d.\n", currentNextPort)
+			if framework != FrameworkFiber {
+				m.Settings.Framework = framework
+			}
+			return registerAuthService(m)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", ManifestFile, err)
+		}
+		fmt.Printf("Next auto-assigned port will start from %d.\n", manifest.Settings.NextPort)
+
+		// Remember what was generated so 'gores upgrade' can merge future template changes.
+		if err := recordBaselines(files); err != nil {
+			return fmt.Errorf("failed to record upgrade baselines: %w", err)
 		}
 
 		fmt.Println("gores project initialized successfully! 🎉")
@@ -94,15 +214,68 @@ var initCmd = &cobra.Command{
 	},
 }
 
+// registerAuthService records the default auth service in the manifest and keeps its port
+// out of automatic assignment.
+func registerAuthService(m *Manifest) error {
+	if m.Service(authServiceName) == nil {
+		if err := m.AddService(newServiceEntry(authServiceName, authServicePort, TemplateAuth)); err != nil {
+			return fmt.Errorf("failed to register auth service: %w", err)
+		}
+	}
+	if m.Settings.NextPort <= authServicePort {
+		m.Settings.NextPort = authServicePort + 1
+	}
+	return nil
+}
+
+// previewProjectManifest returns the manifest 'gores init' would start from without
+// writing anything: the existing gores.yaml, the legacy port files, or a new manifest for
+// module.
+func previewProjectManifest(module string) (*Manifest, error) {
+	if _, err := os.Stat(ManifestFile); err == nil {
+		return LoadManifest(ManifestFile)
+	}
+	m, migrated, err := migrateLegacyState(".")
+	if err != nil {
+		return nil, err
+	}
+	if !migrated {
+		m = NewManifest(module)
+	}
+	return m, nil
+}
+
+// writeManifestPreview records the manifest as it would be saved in an in-memory preview.
+func writeManifestPreview(preview *memFS, m *Manifest) error {
+	content, err := encodeManifest(m)
+	if err != nil {
+		return err
+	}
+	return preview.WriteFile(ManifestFile, content, 0644)
+}
+
 // generateCmd is the Cobra command for generating a new microservice.
 var generateCmd = &cobra.Command{
 	Use:   "generate [service-name] [port]",
 	Short: "Generate microservice boilerplate code",
-	Long:  "Generate microservice boilerplate code including router, controller, service, entity, go.mod, Dockerfile, and go.sum.",
+	Long: "Generate microservice boilerplate code including router, controller, service, repositories, entity, tests, go.mod, Dockerfile, and go.sum. " +
+		"Use --field to declare the entity's fields, or --from to generate every entity described in a YAML/JSON schema " +
+		"file (running it again for an existing service merges the schema changes into the generated files). Use --db to " +
+		"persist the service in another database than the project's, such as MongoDB with --db mongo, or to keep its records in memory with --db none. Use --api grpc " +
+		"to serve the entities over gRPC instead of HTTP, from a generated .proto file, or --api graphql to serve them through " +
+		"one GraphQL endpoint with a playground. The HTTP framework is the project's, chosen with 'gores init --framework'. Use --verify " +
+		"to build and vet the generated or regenerated service against the local module cache, and --dry-run or --diff to preview the " +
+		"files that would be written.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
+			if generateFrom != "" {
+				return nil // The schema file names the service.
+			}
 			return fmt.Errorf("requires service name argument")
 		}
+		if !serviceNamePattern.MatchString(args[0]) {
+			return fmt.Errorf("invalid service name '%s': use letters, digits, '-' and '_', starting with a letter", args[0])
+		}
 		if len(args) > 1 {
 			portStr := args[1]
 			if len(portStr) == 0 {
@@ -120,107 +293,249 @@ var generateCmd = &cobra.Command{
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// --- Prerequisite Check ---
-		if err := checkInitPrerequisite(); err != nil {
+		manifest, err := loadProjectManifest()
+		if err != nil {
 			return err
 		}
 		// --- End Prerequisite Check ---
 
-		serviceName := args[0]
-		usedPortsFile := "used_ports.json" // File to track assigned ports
-		servicesDir := "services"          // Base directory for microservices
-
-		// 1. Check if the service directory already exists (delegated to port_management.go).
-		servicePath := filepath.Join(servicesDir, serviceName)
-		if _, err := os.Stat(servicePath); !os.IsNotExist(err) { // Still using os.Stat here for initial check
-			return fmt.Errorf("a service with the name '%s' already exists at %s", serviceName, servicePath)
+		var serviceName string
+		if len(args) > 0 {
+			serviceName = args[0]
+		}
+		preview := generateDryRun || generateDiff
+		if preview && generateVerify {
+			return fmt.Errorf("--verify cannot be combined with --dry-run or --diff")
 		}
 
-		var port int
+		var requestedPort int // 0 requests automatic assignment
 		if len(args) > 1 && args[1] != "" {
-			var err error
-			port, err = strconv.Atoi(args[1])
+			requestedPort, err = strconv.Atoi(args[1])
 			if err != nil {
 				return fmt.Errorf("port must be a valid number: %w", err)
 			}
+		}
 
-			// Check if the user-provided port is already used or occupied (delegated to port_management.go).
-			usedPorts, err := ReadUsedPorts(usedPortsFile) // Call from port_management.go
-			if err != nil {
-				return fmt.Errorf("failed to read used ports file: %w", err)
+		// The framework is a project setting: the shared middleware is generated for it.
+		if generateFramework != "" {
+			if err := checkFramework(generateFramework); err != nil {
+				return fmt.Errorf("invalid --framework: %w", err)
+			}
+			if generateFramework != manifest.Framework() {
+				return fmt.Errorf("project uses framework '%s'; the framework is chosen for the whole project with 'gores init --framework'", manifest.Framework())
 			}
+		}
 
-			if IsPortUsed(usedPorts, port) { // Call from port_management.go
-				return fmt.Errorf("the port '%d' is already assigned to another service", port)
+		opts := projectGeneratorOptions(manifest)
+		if generateDatabase != "" {
+			if err := checkDatabase(generateDatabase); err != nil {
+				return fmt.Errorf("invalid --db: %w", err)
 			}
+			opts.Database = generateDatabase
+		}
+		opts.IDStrategy = generateIDs
+		if generateAPI != "" {
+			if err := checkAPI(generateAPI); err != nil {
+				return fmt.Errorf("invalid --api: %w", err)
+			}
+			opts.API = generateAPI
+		}
 
-			// Also check if the port is currently in use at the OS level
-			addr := fmt.Sprintf(":%d", port)
-			l, err := net.Listen("tcp", addr)
-			if err == nil { // Port is free at OS level
-				l.Close()
-				// Store the user-provided port with the service name (delegated to port_management.go).
-				err = WriteUsedPortForService(port, serviceName, usedPortsFile) // Call from port_management.go
-				if err != nil {
-					return fmt.Errorf("failed to store user-provided port: %w", err)
+		// The entities come from the schema file or from the --field flags.
+		var entities []EntitySpec
+		var schemaPath string
+		if generateFrom != "" {
+			if len(generateFields) > 0 || len(generateRelations) > 0 {
+				return fmt.Errorf("--field and --relation cannot be combined with --from; declare them in the schema file")
+			}
+			schema, specs, err := loadSchemaFile(generateFrom, serviceName)
+			if err != nil {
+				return err
+			}
+			serviceName, entities, schemaPath = schema.Service, specs, filepath.ToSlash(generateFrom)
+			if requestedPort == 0 {
+				requestedPort = schema.Port
+			}
+			if entry := manifest.Service(serviceName); entry != nil {
+				if generateDatabase != "" && generateDatabase != manifest.ServiceDatabase(entry) {
+					return fmt.Errorf("service '%s' uses database '%s'; it cannot be changed with --db", serviceName, manifest.ServiceDatabase(entry))
+				}
+				if generateIDs != "" {
+					if err := checkIDStrategy(generateIDs, manifest.ServiceDatabase(entry)); err != nil {
+						return err
+					}
+					if generateIDs != serviceGeneratorOptions(manifest, entry).IDStrategy {
+						return fmt.Errorf("the ID strategy of service '%s' cannot be changed with --id-strategy", serviceName)
+					}
+				}
+				if generateAPI != "" && generateAPI != serviceAPI(entry) {
+					return fmt.Errorf("service '%s' serves a '%s' API; it cannot be changed with --api", serviceName, serviceAPI(entry))
 				}
-			} else {
-				return fmt.Errorf("the port '%d' is currently in use by another process on your system", port)
+				cmd.SilenceUsage = true
+				files, err := regenerateService(manifest, entry, schemaPath, entities, preview)
+				if err != nil || !generateVerify {
+					return err
+				}
+				return verifyService(serviceName, serviceGeneratorOptions(manifest, entry).Framework, files)
 			}
-
 		} else {
-			// Logic for automatically finding a port (delegated to port_management.go).
-			startPort := 8080                    // Starting port for auto-assignment.
-			nextPortFile := "next_available_port.txt" // File to store the next suggested port
+			fields, err := parseFieldSpecs(toPascalCase(serviceName), generateFields)
+			if err != nil {
+				return err
+			}
+			relations, err := parseRelationSpecs(toPascalCase(serviceName), generateRelations)
+			if err != nil {
+				return err
+			}
+			entity := newEntitySpec(serviceName, fields)
+			entity.Relations = relations
+			entities = []EntitySpec{entity}
+			if err := linkEntities(entities); err != nil {
+				return err
+			}
+		}
+		if err := checkEntityReferences(manifest, serviceName, entities); err != nil {
+			return err
+		}
+		if err := checkServiceDatabase(opts.Database, entities); err != nil {
+			return err
+		}
+		if generateIDs != "" {
+			if err := checkIDStrategy(generateIDs, opts.Database); err != nil {
+				return err
+			}
+		}
+
+		// 1. Check if the service directory already exists.
+		servicePath := filepath.Join(servicesDir, serviceName)
+		if _, err := os.Stat(servicePath); !os.IsNotExist(err) {
+			return fmt.Errorf("a service with the name '%s' already exists at %s", serviceName, servicePath)
+		}
 
-			p, err := ReadAndIncrementPortWithUsed(startPort, serviceName, nextPortFile, usedPortsFile) // Call from port_management.go
+		if preview {
+			// Pick the port on the in-memory manifest only; nothing is reserved.
+			entry, err := reserveInManifest(manifest, serviceName, requestedPort, TemplateGeneric)
 			if err != nil {
-				return fmt.Errorf("failed to get next available port: %w", err)
+				return err
+			}
+			recordServiceEntities(manifest.Service(serviceName), schemaPath, entities)
+			manifest.Service(serviceName).Database = generateDatabase
+			manifest.Service(serviceName).IDStrategy = generateIDs
+			manifest.Service(serviceName).API = generateAPI
+			recordProtoNumbers(manifest.Service(serviceName), entities)
+			mem := newMemFS()
+			if _, err := createMicroservice(mem, opts, serviceName, strconv.Itoa(entry.Port), "templates/", entities); err != nil {
+				return fmt.Errorf("failed to generate microservice: %w", err)
+			}
+			if _, err := syncWorkspace(mem, []string{serviceModuleDir(serviceName)}, false); err != nil {
+				return err
 			}
-			port = p
+			if err := writeManifestPreview(mem, manifest); err != nil {
+				return err
+			}
+			mem.Report(generateDiff)
+			return nil
+		}
+
+		// Reserve the port under the project lock (delegated to port_management.go).
+		entry, err := ReserveService(ManifestFile, serviceName, requestedPort, TemplateGeneric)
+		if err != nil {
+			return err
 		}
+		port := entry.Port
 
 		// Generate the generic microservice (delegated to service_generation.go).
-		// createSharedPkg() is implicitly handled as part of createMicroservice if needed.
-		if err := createMicroservice(serviceName, strconv.Itoa(port), "templates/"); err != nil {
+		var newEntityFiles []string
+		for _, e := range entities {
+			path := entityFilePath(e.Name, TemplateGeneric)
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				newEntityFiles = append(newEntityFiles, path)
+			}
+		}
+		files, err := createMicroservice(osFS{}, opts, serviceName, strconv.Itoa(port), "templates/", entities)
+		if err != nil {
+			rollbackService(serviceName, servicePath, newEntityFiles)
 			return fmt.Errorf("failed to generate microservice: %w", err)
 		}
+		if schemaPath != "" || len(generateFields) > 0 || len(generateRelations) > 0 || generateDatabase != "" || generateIDs != "" || generateAPI != "" {
+			// Record the entities and database so that 'gores upgrade' renders the same files.
+			_, err := UpdateManifest(ManifestFile, func(m *Manifest) error {
+				if s := m.Service(serviceName); s != nil {
+					recordServiceEntities(s, schemaPath, entities)
+					s.Database = generateDatabase
+					s.IDStrategy = generateIDs
+					s.API = generateAPI
+					recordProtoNumbers(s, entities)
+				}
+				return nil
+			})
+			if err != nil {
+				rollbackService(serviceName, servicePath, newEntityFiles)
+				return fmt.Errorf("failed to record the entities of '%s' in %s: %w", serviceName, ManifestFile, err)
+			}
+		}
+		if changed, err := syncWorkspace(osFS{}, []string{serviceModuleDir(serviceName)}, false); err != nil {
+			rollbackService(serviceName, servicePath, newEntityFiles)
+			return err
+		} else if changed {
+			fmt.Printf("Added '%s' to %s.\n", serviceModuleDir(serviceName), goWorkFile)
+		}
+
+		if err := recordBaselines(files); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record upgrade baselines for '%s': %v\n", serviceName, err)
+		}
 
 		fmt.Printf("Service '%s' generated successfully on port %s.\n", serviceName, strconv.Itoa(port))
+		if generateAPI == APIGRPC {
+			fmt.Printf("Generate its gRPC code before building it (needs protoc on your PATH):\n  cd %s && go generate ./proto\n", filepath.ToSlash(servicePath))
+		}
+
+		if generateVerify {
+			cmd.SilenceUsage = true                                  // A failed build is not a usage error.
+			return verifyService(serviceName, opts.Framework, files) // Delegated to verify.go
+		}
 		return nil
 	},
 }
 
+// rollbackService undoes a failed generation: it frees the port reserved in the
+// manifest and removes the partially written service directory and the entity files
+// the generation created.
+func rollbackService(serviceName, servicePath string, entityFiles []string) {
+	fmt.Fprintf(os.Stderr, "Generation of '%s' failed, rolling back...\n", serviceName)
+	if err := ReleaseService(ManifestFile, serviceName); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to release port reserved for '%s': %v\n", serviceName, err)
+	}
+	if err := os.RemoveAll(servicePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove '%s': %v\n", servicePath, err)
+	}
+	for _, entityPath := range entityFiles {
+		if err := os.Remove(entityPath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove '%s': %v\n", entityPath, err)
+		}
+	}
+}
+
 // listServicesCmd is the Cobra command to list all services and their ports.
 var listServicesCmd = &cobra.Command{
 	Use:   "list-services",
 	Short: "List all generated services and their ports",
-	Long:  "Displays a list of all microservices that have been generated, along with the ports they are using.",
+	Long:  "Displays a list of all microservices recorded in gores.yaml, along with the ports they are using.",
 	Run: func(cmd *cobra.Command, args []string) {
 		// --- Prerequisite Check ---
-		if err := checkInitPrerequisite(); err != nil {
+		manifest, err := loadProjectManifest()
+		if err != nil {
 			fmt.Fprintln(os.Stderr, err.Error()) // Print error to stderr
-			return // Exit early
+			return                               // Exit early
 		}
 		// --- End Prerequisite Check ---
 
-		usedPortsFile := "used_ports.json"
-
-		usedPorts, err := ReadUsedPorts(usedPortsFile) // Call from port_management.go
-		if err != nil {
-			if os.IsNotExist(err) { // More precise check for file not found
-				fmt.Println("No services have been generated yet (used_ports.json not found).")
-				return
-			}
-			fmt.Fprintf(os.Stderr, "Error reading used ports file: %v\n", err)
-			return
-		}
-
-		if len(usedPorts.Ports) == 0 {
+		if len(manifest.Services) == 0 {
 			fmt.Println("No services have been generated yet.")
 		} else {
 			fmt.Println("--- Generated Services ---")
-			for _, pInfo := range usedPorts.Ports { // PortInfo struct is also implicitly known
-				fmt.Printf("Service: %-25s Port: %d\n", pInfo.Service, pInfo.Port)
+			for _, s := range manifest.Services {
+				fmt.Printf("Service: %-25s Port: %-6d Template: %s\n", s.Name, s.Port, s.Template)
 			}
 			fmt.Println("--------------------------")
 		}
@@ -261,7 +576,6 @@ var modTidyAllCmd = &cobra.Command{
 		}
 
 		// 2. Iterate through 'services/' directory and run 'go mod tidy' in each service
-		servicesDir := "services"
 		serviceFolders, err := os.ReadDir(servicesDir)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -312,8 +626,24 @@ func runGoModTidy(dir string) error {
 
 // init function to add commands to the root command.
 func init() {
+	initCmd.Flags().StringVar(&initModule, "module", "", "Go module path of the project, e.g. github.com/acme/platform (default \"gores\")")
+	initCmd.Flags().BoolVar(&initReplace, "replace-directives", false, "Also resolve the shared pkg module with replace directives in service go.mod files, for builds without go.work")
+	initCmd.Flags().BoolVar(&initDryRun, "dry-run", false, "List the files that would be created or overwritten without writing anything")
+	initCmd.Flags().BoolVar(&initDiff, "diff", false, "Like --dry-run, and also show a unified diff against existing files")
+	initCmd.Flags().StringVar(&initFramework, "framework", "", "HTTP framework of every service and the shared middleware: fiber, chi, gin, echo or net/http (default \"fiber\")")
+	initCmd.Flags().StringVar(&initDatabase, "db", "", "Database of the auth service and the default for new services: postgres, mysql or sqlite (default \"postgres\")")
 	rootCmd.AddCommand(initCmd)
+	generateCmd.Flags().BoolVar(&generateVerify, "verify", false, "Run 'go build' and 'go vet' on the generated or regenerated service using the local module cache")
+	generateCmd.Flags().BoolVar(&generateDryRun, "dry-run", false, "List the files that would be created or overwritten without writing anything")
+	generateCmd.Flags().BoolVar(&generateDiff, "diff", false, "Like --dry-run, and also show a unified diff against existing files")
+	generateCmd.Flags().StringVar(&generateFrom, "from", "", "Generate the entities described in a YAML/JSON schema file, or merge its changes into an existing service")
+	generateCmd.Flags().StringArrayVar(&generateRelations, "relation", nil, "Entity relation as name:kind:entity[:optional], kind being belongs-to, has-many or many-to-many, e.g. customer:belongs-to:user; repeatable")
+	generateCmd.Flags().StringVar(&generateDatabase, "db", "", "Database the service persists its entities with: postgres, mysql, sqlite, mongo or none for in-memory storage (default: the project's database)")
+	generateCmd.Flags().StringVar(&generateIDs, "id-strategy", "", "How a --db mongo service generates document IDs: objectid or uuid (default \"objectid\")")
+	generateCmd.Flags().StringVar(&generateAPI, "api", "", "API the service serves its entities through: http, grpc for gRPC services generated from a .proto file, or graphql for a GraphQL endpoint (default \"http\")")
+	generateCmd.Flags().StringVar(&generateFramework, "framework", "", "HTTP framework of the service; must be the project's, which is chosen with 'gores init --framework' (default: the project's framework)")
+	generateCmd.Flags().StringArrayVar(&generateFields, "field", nil, "Entity field as name:type[:modifier...], e.g. total:decimal:required or status:enum(pending,paid); repeatable")
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(listServicesCmd)
 	rootCmd.AddCommand(modTidyAllCmd)
-}
\ No newline at end of file
+}
@@ -0,0 +1,403 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ManifestFile is the project manifest. Its presence marks a directory as a gores project.
+	ManifestFile = "gores.yaml"
+	// ManifestVersion is the schema version written to new manifests.
+	ManifestVersion = 1
+
+	// Legacy state files that are migrated into the manifest on first load.
+	legacyUsedPortsFile = "used_ports.json"
+	legacyNextPortFile  = "next_available_port.txt"
+
+	defaultModulePath = "gores"
+	defaultBasePort   = 8080
+)
+
+// Template kinds recorded for each service in the manifest.
+const (
+	TemplateAuth    = "auth"
+	TemplateGeneric = "generic"
+)
+
+// Manifest is the typed representation of gores.yaml.
+type Manifest struct {
+	Version  int             `yaml:"version"`
+	Module   string          `yaml:"module"`
+	Settings ProjectSettings `yaml:"settings"`
+	Services []ServiceEntry  `yaml:"services"`
+}
+
+// ProjectSettings holds project-wide generator settings.
+type ProjectSettings struct {
+	BasePort int `yaml:"base_port"` // First port considered for automatic assignment
+	NextPort int `yaml:"next_port"` // Next port to try for automatic assignment
+
+	// ReplaceDirectives makes service go.mod files resolve the shared pkg module with a
+	// replace directive, for builds that do not see go.work (e.g. Docker).
+	ReplaceDirectives bool `yaml:"replace_directives,omitempty"`
+
+	// Database is the database new services persist their entities with; empty means postgres.
+	Database string `yaml:"database,omitempty"`
+
+	// Framework is the HTTP framework every service and the shared middleware are generated
+	// for; empty means fiber.
+	Framework string `yaml:"framework,omitempty"`
+}
+
+// ServiceEntry describes a single generated service.
+type ServiceEntry struct {
+	Name         string    `yaml:"name"`
+	Port         int       `yaml:"port"`
+	Template     string    `yaml:"template"`
+	GeneratedAt  time.Time `yaml:"generated_at,omitempty"`
+	GoresVersion string    `yaml:"gores_version,omitempty"`
+	Fields       []string  `yaml:"fields,omitempty"`      // Canonical --field specs of the service's entity
+	Relations    []string  `yaml:"relations,omitempty"`   // Canonical --relation specs of the service's entity
+	Database     string    `yaml:"database,omitempty"`    // Database chosen with --db; empty means the project default
+	IDStrategy   string    `yaml:"id_strategy,omitempty"` // ID strategy of MongoDB services chosen with --id-strategy
+	API          string    `yaml:"api,omitempty"`         // API chosen with --api; empty means HTTP
+
+	// ProtoFields holds the field numbers of the .proto messages of a gRPC service, by entity.
+	ProtoFields map[string]ProtoNumbers `yaml:"proto_fields,omitempty"`
+
+	// Schema-driven services record their source file and every entity it declared.
+	Schema   string         `yaml:"schema,omitempty"`
+	Entities []SchemaEntity `yaml:"entities,omitempty"`
+}
+
+// NewManifest returns an empty manifest for the given module path with default settings.
+func NewManifest(module string) *Manifest {
+	if module == "" {
+		module = defaultModulePath
+	}
+	return &Manifest{
+		Version: ManifestVersion,
+		Module:  module,
+		Settings: ProjectSettings{
+			BasePort: defaultBasePort,
+			NextPort: defaultBasePort,
+		},
+	}
+}
+
+// LoadManifest reads the manifest at path. If it does not exist but the legacy
+// used_ports.json / next_available_port.txt files do, they are migrated into a new
+// manifest which is written to path under the project lock. An error satisfying
+// os.IsNotExist is returned when neither is present.
+func LoadManifest(path string) (*Manifest, error) {
+	m, err := loadManifest(path, false)
+	if !os.IsNotExist(err) || !hasLegacyState(filepath.Dir(path)) {
+		return m, err
+	}
+	err = withProjectLock(filepath.Dir(path), func() error {
+		var err error
+		m, err = loadManifest(path, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// loadManifest reads the manifest at path. When migrate is set, a missing manifest is
+// migrated from the legacy files, if any, and saved; the caller holds the project lock.
+func loadManifest(path string, migrate bool) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if !migrate {
+			return nil, err
+		}
+		m, migrated, merr := migrateLegacyState(filepath.Dir(path))
+		if merr != nil {
+			return nil, merr
+		}
+		if !migrated {
+			return nil, err
+		}
+		if err := SaveManifest(path, m); err != nil {
+			return nil, err
+		}
+		removeLegacyState(filepath.Dir(path))
+		fmt.Printf("Migrated %s and %s into %s.\n", legacyUsedPortsFile, legacyNextPortFile, path)
+		return m, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read manifest '%s': %w", path, err)
+	}
+
+	m := &Manifest{}
+	if err := yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest '%s': %w", path, err)
+	}
+	if m.Version > ManifestVersion {
+		return nil, fmt.Errorf("manifest '%s' has version %d, but this gores only understands up to version %d; please upgrade gores", path, m.Version, ManifestVersion)
+	}
+	m.applyDefaults()
+	return m, nil
+}
+
+// SaveManifest writes the manifest to path.
+func SaveManifest(path string, m *Manifest) error {
+	content, err := encodeManifest(m)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, content, 0644)
+}
+
+// encodeManifest returns the gores.yaml representation of m.
+func encodeManifest(m *Manifest) ([]byte, error) {
+	m.Version = ManifestVersion
+	content, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	header := []byte("# Generated and maintained by gores. Edit with care.\n")
+	return append(header, content...), nil
+}
+
+// UpdateManifest loads the manifest at path while holding the project lock, applies fn
+// and atomically saves the result. Nothing is written if fn returns an error.
+func UpdateManifest(path string, fn func(m *Manifest) error) (*Manifest, error) {
+	return updateManifest(path, false, fn)
+}
+
+// CreateOrUpdateManifest is like UpdateManifest but starts from a new manifest when
+// none exists at path.
+func CreateOrUpdateManifest(path string, fn func(m *Manifest) error) (*Manifest, error) {
+	return updateManifest(path, true, fn)
+}
+
+func updateManifest(path string, create bool, fn func(m *Manifest) error) (*Manifest, error) {
+	var m *Manifest
+	err := withProjectLock(filepath.Dir(path), func() error {
+		var err error
+		m, err = loadManifest(path, true)
+		if os.IsNotExist(err) && create {
+			m = NewManifest(defaultModulePath)
+		} else if err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+		return SaveManifest(path, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Manifest) applyDefaults() {
+	if m.Module == "" {
+		m.Module = defaultModulePath
+	}
+	if m.Settings.BasePort == 0 {
+		m.Settings.BasePort = defaultBasePort
+	}
+	if m.Settings.NextPort < m.Settings.BasePort {
+		m.Settings.NextPort = m.Settings.BasePort
+	}
+}
+
+// Service returns the entry for the named service, or nil if it is not registered.
+func (m *Manifest) Service(name string) *ServiceEntry {
+	for i := range m.Services {
+		if m.Services[i].Name == name {
+			return &m.Services[i]
+		}
+	}
+	return nil
+}
+
+// DefaultDatabase returns the database new services use unless --db says otherwise.
+func (m *Manifest) DefaultDatabase() string {
+	if m.Settings.Database == "" {
+		return DatabasePostgres
+	}
+	return m.Settings.Database
+}
+
+// Framework returns the HTTP framework of the project's services.
+func (m *Manifest) Framework() string {
+	if m.Settings.Framework == "" {
+		return FrameworkFiber
+	}
+	return m.Settings.Framework
+}
+
+// ServiceDatabase returns the database the given service persists its entities with.
+func (m *Manifest) ServiceDatabase(entry *ServiceEntry) string {
+	if entry.Database == "" {
+		return m.DefaultDatabase()
+	}
+	return entry.Database
+}
+
+// Databases lists the databases the project connects to, the default first, so that the
+// shared pkg module carries a connection package for each of them.
+func (m *Manifest) Databases() []string {
+	databases := []string{m.DefaultDatabase()}
+	for i := range m.Services {
+		db := m.ServiceDatabase(&m.Services[i])
+		if _, ok := databaseBackends[db]; ok && !containsString(databases, db) {
+			databases = append(databases, db)
+		}
+	}
+	return databases
+}
+
+// AddService registers a new service. It fails if the name or port is already taken.
+func (m *Manifest) AddService(entry ServiceEntry) error {
+	if m.Service(entry.Name) != nil {
+		return fmt.Errorf("service '%s' is already registered in %s", entry.Name, ManifestFile)
+	}
+	if owner := m.PortOwner(entry.Port); owner != "" {
+		return fmt.Errorf("the port '%d' is already assigned to service '%s'", entry.Port, owner)
+	}
+	m.Services = append(m.Services, entry)
+	return nil
+}
+
+// RemoveService unregisters the named service and reports whether it was present.
+func (m *Manifest) RemoveService(name string) bool {
+	for i := range m.Services {
+		if m.Services[i].Name == name {
+			m.Services = append(m.Services[:i], m.Services[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// PortOwner returns the name of the service using port, or "" if the port is free.
+func (m *Manifest) PortOwner(port int) string {
+	for _, s := range m.Services {
+		if s.Port == port {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// IsPortUsed checks if a port is assigned to any service in the manifest.
+func (m *Manifest) IsPortUsed(port int) bool {
+	return m.PortOwner(port) != ""
+}
+
+// migrateLegacyState builds a manifest from used_ports.json and next_available_port.txt
+// in dir. It reports false if neither legacy file exists.
+func migrateLegacyState(dir string) (*Manifest, bool, error) {
+	usedPath := filepath.Join(dir, legacyUsedPortsFile)
+	nextPath := filepath.Join(dir, legacyNextPortFile)
+
+	usedContent, usedErr := os.ReadFile(usedPath)
+	nextContent, nextErr := os.ReadFile(nextPath)
+	if os.IsNotExist(usedErr) && os.IsNotExist(nextErr) {
+		return nil, false, nil
+	}
+	if usedErr != nil && !os.IsNotExist(usedErr) {
+		return nil, false, fmt.Errorf("failed to read legacy ports file '%s': %w", usedPath, usedErr)
+	}
+
+	m := NewManifest(readLegacyModulePath(dir))
+
+	if usedErr == nil {
+		var legacy struct {
+			Ports []struct {
+				Port    int    `json:"port"`
+				Service string `json:"service"`
+			} `json:"used_ports"`
+		}
+		if err := json.Unmarshal(usedContent, &legacy); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal legacy ports file '%s': %w", usedPath, err)
+		}
+		for _, p := range legacy.Ports {
+			if m.Service(p.Service) != nil {
+				continue // The legacy file could contain duplicates; keep the first.
+			}
+			entry := ServiceEntry{
+				Name:     p.Service,
+				Port:     p.Port,
+				Template: TemplateGeneric,
+			}
+			if p.Service == authServiceName {
+				entry.Template = TemplateAuth
+			}
+			if info, err := os.Stat(filepath.Join(dir, servicesDir, p.Service)); err == nil {
+				entry.GeneratedAt = info.ModTime().UTC().Truncate(time.Second)
+			}
+			m.Services = append(m.Services, entry)
+		}
+	}
+
+	for _, s := range m.Services {
+		if s.Port >= m.Settings.NextPort {
+			m.Settings.NextPort = s.Port + 1
+		}
+	}
+	if nextErr == nil {
+		if p, err := strconv.Atoi(strings.TrimSpace(string(nextContent))); err == nil && p > m.Settings.NextPort {
+			m.Settings.NextPort = p
+		}
+	}
+
+	sort.SliceStable(m.Services, func(i, j int) bool { return m.Services[i].Port < m.Services[j].Port })
+	return m, true, nil
+}
+
+// hasLegacyState reports whether dir holds either legacy state file.
+func hasLegacyState(dir string) bool {
+	for _, name := range []string{legacyUsedPortsFile, legacyNextPortFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLegacyState deletes the legacy state files once their content lives in the manifest.
+func removeLegacyState(dir string) {
+	for _, name := range []string{legacyUsedPortsFile, legacyNextPortFile} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove legacy file '%s': %v\n", name, err)
+		}
+	}
+}
+
+// readLegacyModulePath derives the project module path from pkg/go.mod, which older
+// versions of gores created as "module <root>/pkg".
+func readLegacyModulePath(dir string) string {
+	f, err := os.Open(filepath.Join(dir, "pkg", "go.mod"))
+	if err != nil {
+		return defaultModulePath
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			module := strings.TrimSpace(strings.TrimPrefix(line, "module "))
+			return strings.TrimSuffix(module, "/pkg")
+		}
+	}
+	return defaultModulePath
+}
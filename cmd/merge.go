@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"strings"
+)
+
+// mergeLabels name the three sides in conflict markers.
+type mergeLabels struct {
+	ours, base, theirs string
+}
+
+// merge3 performs a line-based three-way merge of ours and theirs, which both derive from
+// base. Hunks changed on only one side are taken from that side; hunks changed identically
+// on both sides are taken once; anything else is written as a diff3-style conflict:
+//
+//	<<<<<<< ours
+//	...
+//	||||||| base
+//	...
+//	=======
+//	...
+//	>>>>>>> theirs
+//
+// It returns the merged text and the number of conflicts.
+func merge3(base, ours, theirs []byte, labels mergeLabels) ([]byte, int) {
+	baseLines := splitLines(string(base))
+	ourLines := splitLines(string(ours))
+	theirLines := splitLines(string(theirs))
+
+	// For every base line, the index of the matching line on each side (or -1).
+	ourMatch := matchBaseLines(diffLines(baseLines, ourLines), len(baseLines))
+	theirMatch := matchBaseLines(diffLines(baseLines, theirLines), len(baseLines))
+
+	var out strings.Builder
+	conflicts := 0
+	i, j, k := 0, 0, 0 // Positions in base, ours and theirs
+	for i < len(baseLines) || j < len(ourLines) || k < len(theirLines) {
+		// Stable line: unchanged on both sides.
+		if i < len(baseLines) && ourMatch[i] == j && theirMatch[i] == k {
+			out.WriteString(baseLines[i])
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+
+		// Unstable chunk: up to the next base line that both sides kept.
+		next := i
+		for next < len(baseLines) && (ourMatch[next] < 0 || theirMatch[next] < 0) {
+			next++
+		}
+		ourEnd, theirEnd := len(ourLines), len(theirLines)
+		if next < len(baseLines) {
+			ourEnd, theirEnd = ourMatch[next], theirMatch[next]
+		}
+		baseChunk, ourChunk, theirChunk := baseLines[i:next], ourLines[j:ourEnd], theirLines[k:theirEnd]
+
+		switch {
+		case equalLines(ourChunk, baseChunk):
+			writeLines(&out, theirChunk)
+		case equalLines(theirChunk, baseChunk), equalLines(ourChunk, theirChunk):
+			writeLines(&out, ourChunk)
+		default:
+			conflicts++
+			writeMarker(&out, "<<<<<<< "+labels.ours)
+			writeLines(&out, ourChunk)
+			writeMarker(&out, "||||||| "+labels.base)
+			writeLines(&out, baseChunk)
+			writeMarker(&out, "=======")
+			writeLines(&out, theirChunk)
+			writeMarker(&out, ">>>>>>> "+labels.theirs)
+		}
+		i, j, k = next, ourEnd, theirEnd
+	}
+	return []byte(out.String()), conflicts
+}
+
+// matchBaseLines turns an edit script from base into a slice mapping each base line to the
+// index of the line it was kept as, or -1 if it was deleted.
+func matchBaseLines(ops []lineOp, baseLen int) []int {
+	match := make([]int, baseLen)
+	i, j := 0, 0
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			match[i] = j
+			i++
+			j++
+		case '-':
+			match[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+	return match
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
+
+// writeMarker writes a conflict marker line, starting a new line first if the preceding
+// chunk ended without a newline.
+func writeMarker(out *strings.Builder, marker string) {
+	if s := out.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		out.WriteByte('\n')
+	}
+	out.WriteString(marker + "\n")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	labels := mergeLabels{ours: "yours", base: "baseline", theirs: "gores"}
+	base := "a\nb\nc\nd\ne\n"
+
+	cases := []struct {
+		name          string
+		ours, theirs  string
+		want          string
+		wantConflicts int
+	}{
+		{"only theirs changed", base, "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", 0},
+		{"only ours changed", "a\nb\nc\nd\nE\n", base, "a\nb\nc\nd\nE\n", 0},
+		{"disjoint changes", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\ne\nf\n", "A\nb\nc\nd\ne\nf\n", 0},
+		{"identical changes", "a\nb\nX\nd\ne\n", "a\nb\nX\nd\ne\n", "a\nb\nX\nd\ne\n", 0},
+		{
+			"overlapping changes", "a\nb\nours\nd\ne\n", "a\nb\ntheirs\nd\ne\n",
+			"a\nb\n<<<<<<< yours\nours\n||||||| baseline\nc\n=======\ntheirs\n>>>>>>> gores\nd\ne\n", 1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, conflicts := merge3([]byte(base), []byte(tc.ours), []byte(tc.theirs), labels)
+			if string(got) != tc.want || conflicts != tc.wantConflicts {
+				t.Fatalf("got %d conflict(s):\n%s\nwant %d:\n%s", conflicts, got, tc.wantConflicts, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// migrationVersionLayout formats the UTC creation time that prefixes migration files.
+const migrationVersionLayout = "20060102150405"
+
+// migrationRunner is the migration command of the shared pkg module, relative to pkg/.
+var migrationRunner = filepath.Join("cmd", "migrate")
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var (
+	migrationEmpty bool
+	migrateSteps   int
+)
+
+// migrationsDir is where the SQL migrations of a service live.
+func migrationsDir(serviceName string) string {
+	return filepath.Join(servicesDir, serviceName, "migrations")
+}
+
+// nextMigrationVersion returns the version of a migration created at now: its timestamp,
+// moved past the latest existing migration so that versions stay unique and ordered.
+func nextMigrationVersion(existing []string, now time.Time) string {
+	now = now.Truncate(time.Second)
+	for _, file := range existing {
+		prefix, _, _ := strings.Cut(filepath.Base(file), "_")
+		if latest, err := time.Parse(migrationVersionLayout, prefix); err == nil && !latest.Before(now) {
+			now = latest.Add(time.Second)
+		}
+	}
+	return now.Format(migrationVersionLayout)
+}
+
+// migrationData is the input of the migration_up.sql and migration_down.sql templates.
+type migrationData struct {
+	Service string
+	Version string
+	Name    string
+	Tables  []sqlTable // Tables created by the migration and dropped when it is reverted
+	Up      []string   // Further statements of the migration
+	Down    []string   // Statements reverting Up, run before the tables are dropped
+}
+
+// DropOrder lists the quoted names of the tables in the order the down migration drops them.
+func (d migrationData) DropOrder() []string {
+	names := make([]string, 0, len(d.Tables))
+	for i := len(d.Tables) - 1; i >= 0; i-- {
+		names = append(names, d.Tables[i].QuotedName())
+	}
+	return names
+}
+
+// sqlTable is a CREATE TABLE statement with the indexes created alongside it.
+type sqlTable struct {
+	Name    string
+	Columns []string // Column definitions and table constraints
+	Indexes []string // CREATE INDEX statements
+}
+
+// QuotedName is the table name as written in SQL statements.
+func (t sqlTable) QuotedName() string {
+	return quoteIdent(t.Name)
+}
+
+// quoteIdent quotes a table, column or index name for PostgreSQL, the only database with
+// versioned migrations, so that names that are reserved words, such as "order" or "user",
+// are accepted. SQLite accepts the same quoting.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// createMigration writes the up and down SQL files of a new migration. When entities are
+// given, the migration creates their tables.
+func createMigration(fsys projectFS, serviceName, version, name string, entities []EntitySpec) (generatedFiles, error) {
+	return writeMigration(fsys, migrationData{Service: serviceName, Version: version, Name: name, Tables: sqlTables(entities)})
+}
+
+// writeMigration renders the up and down SQL files of data.
+func writeMigration(fsys projectFS, data migrationData) (generatedFiles, error) {
+	files := generatedFiles{}
+	dir := migrationsDir(data.Service)
+	if err := fsys.MkdirAll(dir, os.ModePerm); err != nil {
+		return files, fmt.Errorf("failed to create %s folder: %w", filepath.ToSlash(dir), err)
+	}
+
+	version, name := data.Version, data.Name
+	for _, direction := range []string{"up", "down"} {
+		tmpl := fmt.Sprintf("templates/migration_%s.sql.tmpl", direction)
+		output := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		if _, err := fsys.Stat(output); err == nil {
+			return files, fmt.Errorf("%s already exists", filepath.ToSlash(output))
+		}
+		if err := writeTemplate(fsys, tmpl, output, data); err != nil {
+			return files, err
+		}
+		files[output] = tmpl
+		reportf(fsys, "Generated: %s\n", filepath.ToSlash(output))
+	}
+	return files, nil
+}
+
+// sqlTables returns the tables of entities, ordered so that tables come after the tables
+// their foreign keys reference, followed by the join tables of many-to-many relations.
+// Foreign keys to entities of other services get no constraint: their tables are created
+// by the other service's migrations.
+func sqlTables(entities []EntitySpec) []sqlTable {
+	types := map[string]EntitySpec{}
+	references := map[string]map[string]string{} // entity type -> foreign key Go name -> target type
+	for _, e := range entities {
+		types[e.Type()] = e
+		references[e.Type()] = map[string]string{}
+	}
+	for _, e := range entities {
+		for _, r := range e.Relations {
+			switch r.Kind {
+			case RelationBelongsTo:
+				references[e.Type()][r.ForeignKey] = r.TargetType()
+			case RelationHasMany:
+				if refs, ok := references[r.TargetType()]; ok {
+					refs[r.ForeignKey] = e.Type()
+				}
+			}
+		}
+	}
+
+	// Place every entity after the entities it references, keeping the declared order
+	// otherwise. Entities caught in a reference cycle are placed as declared.
+	var ordered []EntitySpec
+	created := map[string]bool{}
+	for len(ordered) < len(entities) {
+		next := -1
+		for i, e := range entities {
+			if created[e.Type()] {
+				continue
+			}
+			ready := true
+			for _, target := range references[e.Type()] {
+				if _, local := types[target]; local && target != e.Type() && !created[target] {
+					ready = false
+				}
+			}
+			if ready || next == -1 {
+				next = i
+			}
+			if ready {
+				break
+			}
+		}
+		ordered = append(ordered, entities[next])
+		created[entities[next].Type()] = true
+	}
+
+	var tables, joins []sqlTable
+	created = map[string]bool{}
+	for _, e := range ordered {
+		created[e.Type()] = true
+		table := sqlTable{Name: e.Table(), Columns: []string{primaryKeyColumn}}
+		for _, f := range e.AllFields() {
+			column := quoteIdent(f.Column()) + " " + f.SQLType()
+			if f.Required {
+				column += " NOT NULL"
+			}
+			if f.Kind == FieldEnum {
+				column += fmt.Sprintf(" CHECK (%s IN ('%s'))", quoteIdent(f.Column()), strings.Join(f.EnumValues, "', '"))
+			}
+			if target, ok := references[e.Type()][f.GoName()]; ok && created[target] {
+				column += " REFERENCES " + referencedID(types[target].Table())
+			}
+			table.Columns = append(table.Columns, column)
+
+			index := modelIndex{Name: fmt.Sprintf("idx_%s_%s", table.Name, f.Column()), Column: f.Column(), Unique: f.Unique}
+			if f.Unique || f.Indexed {
+				table.Indexes = append(table.Indexes, index.Statement(table.Name))
+			}
+		}
+		table.Columns = append(table.Columns, quoteIdent("created_at")+" timestamptz", quoteIdent("updated_at")+" timestamptz")
+		tables = append(tables, table)
+
+		for _, r := range e.Relations {
+			if r.Kind != RelationManyToMany {
+				continue
+			}
+			ownerKey, targetKey := r.JoinKeys()
+			owner, target := snakeCase(ownerKey), snakeCase(targetKey)
+			join := sqlTable{Name: r.JoinTable(), Columns: []string{
+				fmt.Sprintf("%s uuid NOT NULL REFERENCES %s ON DELETE CASCADE", quoteIdent(owner), referencedID(e.Table())),
+				quoteIdent(target) + " uuid NOT NULL",
+			}}
+			if t, ok := types[r.TargetType()]; ok {
+				join.Columns[1] += fmt.Sprintf(" REFERENCES %s ON DELETE CASCADE", referencedID(t.Table()))
+			}
+			join.Columns = append(join.Columns, fmt.Sprintf("PRIMARY KEY (%s, %s)", quoteIdent(owner), quoteIdent(target)))
+			joins = append(joins, join)
+		}
+	}
+	return append(tables, joins...)
+}
+
+// primaryKeyColumn is the definition of the UUID primary key of every entity table.
+var primaryKeyColumn = quoteIdent("id") + " uuid PRIMARY KEY DEFAULT gen_random_uuid()"
+
+// referencedID is the target of a foreign key to the primary key of table.
+func referencedID(table string) string {
+	return fmt.Sprintf("%s (%s)", quoteIdent(table), quoteIdent("id"))
+}
+
+// authUserEntity describes the User entity of the auth service for its migrations.
+func authUserEntity() EntitySpec {
+	fields, _ := parseFieldSpecs("User", []string{"email:string:required:unique", "passwordHash:string:required", "name:string:optional"})
+	return newEntitySpec("user", fields)
+}
+
+// checkVersionedMigrations fails for services whose tables are not managed by versioned
+// migrations: the migration runner only speaks PostgreSQL, and services on other
+// databases create their tables with AutoMigrate at startup.
+func checkVersionedMigrations(m *Manifest, entry *ServiceEntry) error {
+	switch db := m.ServiceDatabase(entry); db {
+	case DatabasePostgres:
+		return nil
+	case DatabaseNone:
+		return fmt.Errorf("service '%s' keeps its records in memory and has no database to migrate", entry.Name)
+	case DatabaseMongo:
+		return fmt.Errorf("service '%s' uses mongo, which has no SQL schema to migrate; its indexes are created at startup", entry.Name)
+	default:
+		return fmt.Errorf("service '%s' uses %s, whose tables the service creates with AutoMigrate at startup; versioned migrations are only supported for postgres services", entry.Name, db)
+	}
+}
+
+// migrationCmd groups the commands that write SQL migrations.
+var migrationCmd = &cobra.Command{
+	Use:   "migration",
+	Short: "Create versioned SQL migrations",
+}
+
+// migrationNewCmd is the Cobra command for writing a new migration of a service.
+var migrationNewCmd = &cobra.Command{
+	Use:   "new [service-name] [migration-name]",
+	Short: "Create a timestamped pair of up/down SQL migration files",
+	Long: "Writes services/<service>/migrations/<timestamp>_<name>.up.sql and .down.sql. The first migration of a " +
+		"service creates the tables of its entities; later ones are empty for you to fill in. Apply them with 'gores migrate up'.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --- Prerequisite Check ---
+		manifest, err := loadProjectManifest()
+		if err != nil {
+			return err
+		}
+		// --- End Prerequisite Check ---
+
+		serviceName := args[0]
+		entry := manifest.Service(serviceName)
+		if entry == nil {
+			return fmt.Errorf("service '%s' is not registered in %s", serviceName, ManifestFile)
+		}
+		if err := checkVersionedMigrations(manifest, entry); err != nil {
+			return err
+		}
+		name := snakeCase(args[1])
+		if !migrationNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid migration name '%s': use letters, digits, '-' or '_', starting with a letter", args[1])
+		}
+
+		existing, _ := filepath.Glob(filepath.Join(migrationsDir(serviceName), "*.up.sql"))
+		var entities []EntitySpec
+		if len(existing) == 0 && !migrationEmpty {
+			switch entry.Template {
+			case TemplateAuth:
+				entities = []EntitySpec{authUserEntity()}
+			case TemplateGeneric:
+				if entities, err = serviceEntities(entry); err != nil {
+					return err
+				}
+			}
+		}
+
+		version := nextMigrationVersion(existing, time.Now().UTC())
+		if _, err := createMigration(osFS{}, serviceName, version, name, entities); err != nil {
+			return err
+		}
+		if len(entities) > 0 {
+			fmt.Printf("The migration creates the tables of service '%s'. Review it, then run 'gores migrate up %s'.\n", serviceName, serviceName)
+		}
+		return nil
+	},
+}
+
+// migrateCmd groups the commands that apply migrations to the configured database.
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply, revert or list the SQL migrations of services",
+	Long: "Runs the migration runner of the shared pkg module (pkg/cmd/migrate) against the PostgreSQL database " +
+		"configured by the POSTGRES_* variables in the environment or the project's .env file.",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up [service-name]",
+	Short: "Apply the pending migrations of a service, or of every service",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMigrations("up", args)
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [service-name]",
+	Short: "Revert the latest applied migrations of a service",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if migrateSteps < 1 {
+			return fmt.Errorf("--steps must be at least 1")
+		}
+		return runMigrations("down", args)
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status [service-name]",
+	Short: "List the applied and pending migrations of a service, or of every service",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMigrations("status", args)
+	},
+}
+
+// runMigrations runs the migration runner for the named service, or for every registered
+// service that has migrations.
+func runMigrations(action string, args []string) error {
+	// --- Prerequisite Check ---
+	manifest, err := loadProjectManifest()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(pkgModuleDir, migrationRunner, "main.go")); err != nil {
+		return fmt.Errorf("the migration runner %s is missing; run 'gores upgrade pkg' to add it",
+			filepath.ToSlash(filepath.Join(pkgModuleDir, migrationRunner)))
+	}
+	// --- End Prerequisite Check ---
+
+	var services []string
+	if len(args) == 1 {
+		entry := manifest.Service(args[0])
+		if entry == nil {
+			return fmt.Errorf("service '%s' is not registered in %s", args[0], ManifestFile)
+		}
+		if err := checkVersionedMigrations(manifest, entry); err != nil {
+			return err
+		}
+		if _, err := os.Stat(migrationsDir(args[0])); err != nil {
+			return fmt.Errorf("service '%s' has no migrations; create one with 'gores migration new %s <name>'", args[0], args[0])
+		}
+		services = args
+	} else {
+		for i := range manifest.Services {
+			s := &manifest.Services[i]
+			if checkVersionedMigrations(manifest, s) != nil {
+				continue
+			}
+			if _, err := os.Stat(migrationsDir(s.Name)); err == nil {
+				services = append(services, s.Name)
+			}
+		}
+		if len(services) == 0 {
+			fmt.Println("No service has migrations yet; create one with 'gores migration new <service> <name>'.")
+			return nil
+		}
+	}
+
+	for _, name := range services {
+		dir, err := filepath.Abs(migrationsDir(name))
+		if err != nil {
+			return err
+		}
+		if len(services) > 1 {
+			fmt.Printf("==> %s\n", name)
+		}
+		runArgs := []string{"run", "./" + filepath.ToSlash(migrationRunner), "-service", name, "-dir", dir}
+		if action == "down" {
+			runArgs = append(runArgs, "-steps", strconv.Itoa(migrateSteps))
+		}
+		runner := exec.Command("go", append(runArgs, action)...)
+		runner.Dir = pkgModuleDir
+		runner.Stdout = os.Stdout
+		runner.Stderr = os.Stderr
+		if err := runner.Run(); err != nil {
+			return fmt.Errorf("migrate %s failed for service '%s': %w", action, name, err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	migrationNewCmd.Flags().BoolVar(&migrationEmpty, "empty", false, "Write empty migration files even for the service's first migration")
+	migrationCmd.AddCommand(migrationNewCmd)
+	rootCmd.AddCommand(migrationCmd)
+
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "Number of migrations to revert")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
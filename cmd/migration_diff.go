@@ -0,0 +1,671 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var migrationDiffDryRun bool
+
+// modelTable is the table an entity struct in pkg/entities maps to, as gorm sees it.
+type modelTable struct {
+	Struct  string
+	Name    string
+	Columns []modelColumn
+	Indexes []modelIndex
+	Joins   []modelJoin // many2many join tables of the struct's associations
+}
+
+// modelColumn is a column derived from a struct field and its gorm tag.
+type modelColumn struct {
+	Name       string
+	Type       string
+	Nullable   bool
+	Default    string // SQL default declared with the gorm default setting, if any
+	References string // Table the column references, for foreign keys of associations
+}
+
+// Definition is the column definition used in CREATE TABLE and ADD COLUMN.
+func (c modelColumn) Definition() string {
+	def := quoteIdent(c.Name) + " " + c.Type
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.References != "" {
+		def += " REFERENCES " + referencedID(c.References)
+	}
+	return def
+}
+
+// modelIndex is an index declared with the index or uniqueIndex gorm setting.
+type modelIndex struct {
+	Name   string
+	Column string
+	Unique bool
+}
+
+// Statement is the CREATE INDEX statement of the index on table.
+func (i modelIndex) Statement(table string) string {
+	unique := ""
+	if i.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, quoteIdent(i.Name), quoteIdent(table), quoteIdent(i.Column))
+}
+
+// modelJoin is the join table of a many-to-many association.
+type modelJoin struct {
+	Name                    string
+	OwnerColumn, RefColumn  string
+	OwnerTable, TargetTable string
+}
+
+// dbSchema is the report of the migration runner's inspect command.
+type dbSchema struct {
+	Tables  map[string]*dbTable `json:"tables"`
+	Pending []string            `json:"pending"`
+}
+
+type dbTable struct {
+	Columns []dbColumn `json:"columns"`
+	Indexes []dbIndex  `json:"indexes"`
+}
+
+type dbColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+type dbIndex struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// gormSettings parses a gorm struct tag such as "column:total;type:numeric(20,4);not null"
+// into lower-cased keys and their values.
+func gormSettings(tag string) map[string]string {
+	settings := map[string]string{}
+	for _, part := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if key != "" {
+			settings[strings.ToLower(key)] = value
+		}
+	}
+	return settings
+}
+
+// parseEntityModels parses every Go file in dir and returns the tables of the structs
+// declared in files, in declaration order. The other files are only used to resolve
+// associations and named types.
+func parseEntityModels(dir string, files []string) ([]modelTable, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.ToSlash(dir), err)
+	}
+
+	structs := map[string]*ast.StructType{}
+	named := map[string]ast.Expr{} // Non-struct types such as enums, by name
+	tableNames := map[string]string{}
+	declaredIn := map[string]string{}
+	var order []string
+	for _, pkg := range pkgs {
+		for path, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				switch d := decl.(type) {
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						ts, ok := spec.(*ast.TypeSpec)
+						if !ok {
+							continue
+						}
+						if st, ok := ts.Type.(*ast.StructType); ok {
+							structs[ts.Name.Name] = st
+							declaredIn[ts.Name.Name] = filepath.Base(path)
+							order = append(order, ts.Name.Name)
+						} else {
+							named[ts.Name.Name] = ts.Type
+						}
+					}
+				case *ast.FuncDecl:
+					if name, table, ok := tableNameMethod(d); ok {
+						tableNames[name] = table
+					}
+				}
+			}
+		}
+	}
+	tableOf := func(name string) string {
+		if table, ok := tableNames[name]; ok {
+			return table
+		}
+		return pluralize(snakeCase(name))
+	}
+
+	// The structs of files, ordered by file and then by declaration.
+	sortByFile := func(names []string) []string {
+		var sorted []string
+		for _, f := range files {
+			for _, name := range names {
+				if declaredIn[name] == filepath.Base(f) {
+					sorted = append(sorted, name)
+				}
+			}
+		}
+		return sorted
+	}
+
+	var tables []modelTable
+	byName := map[string]int{}
+	for _, name := range sortByFile(order) {
+		byName[name] = len(tables)
+		tables = append(tables, modelTable{Struct: name, Name: tableOf(name)})
+	}
+
+	// Foreign keys of associations, keyed by struct and field: set once all structs are read.
+	references := map[string]map[string]string{}
+	addReference := func(owner, field, table string) {
+		if references[owner] == nil {
+			references[owner] = map[string]string{}
+		}
+		references[owner][field] = table
+	}
+
+	for _, name := range sortByFile(order) {
+		t := &tables[byName[name]]
+		for _, field := range structs[name].Fields.List {
+			if len(field.Names) == 0 {
+				continue // Embedded structs are not used by generated entities.
+			}
+			tag := ""
+			if field.Tag != nil {
+				unquoted, _ := strconv.Unquote(field.Tag.Value)
+				tag = reflect.StructTag(unquoted).Get("gorm")
+			}
+			if tag == "-" || strings.HasPrefix(tag, "-:") {
+				continue
+			}
+			settings := gormSettings(tag)
+			target, collection := associationTarget(field.Type, structs)
+			if target != "" {
+				if joinTable, ok := settings["many2many"]; ok {
+					ownerKey := settings["joinforeignkey"]
+					if ownerKey == "" {
+						ownerKey = name + "ID"
+					}
+					refKey := settings["joinreferences"]
+					if refKey == "" {
+						refKey = target + "ID"
+					}
+					t.Joins = append(t.Joins, modelJoin{
+						Name: joinTable, OwnerColumn: snakeCase(ownerKey), RefColumn: snakeCase(refKey),
+						OwnerTable: t.Name, TargetTable: tableOf(target),
+					})
+				} else if fk := settings["foreignkey"]; fk != "" {
+					if collection {
+						addReference(target, fk, t.Name)
+					} else {
+						addReference(name, fk, tableOf(target))
+					}
+				}
+				continue
+			}
+
+			for _, ident := range field.Names {
+				if !ident.IsExported() {
+					continue
+				}
+				column, err := modelColumnOf(ident.Name, field.Type, settings, named)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", name, ident.Name, err)
+				}
+				if _, primary := settings["primarykey"]; primary {
+					column.Nullable = false
+				}
+				t.Columns = append(t.Columns, column)
+				for _, kind := range []string{"index", "uniqueindex"} {
+					indexName, ok := settings[kind]
+					if !ok {
+						continue
+					}
+					if indexName == "" {
+						indexName = fmt.Sprintf("idx_%s_%s", t.Name, column.Name)
+					}
+					t.Indexes = append(t.Indexes, modelIndex{Name: indexName, Column: column.Name, Unique: kind == "uniqueindex"})
+				}
+			}
+		}
+	}
+
+	// Like the first migration of a service, constrain only foreign keys between its own
+	// tables: the tables of other services are created by their migrations.
+	own := map[string]bool{}
+	for _, t := range tables {
+		own[t.Name] = true
+	}
+	for i := range tables {
+		for j, c := range tables[i].Columns {
+			for field, table := range references[tables[i].Struct] {
+				if snakeCase(field) == c.Name && own[table] {
+					tables[i].Columns[j].References = table
+				}
+			}
+		}
+	}
+	return tables, nil
+}
+
+// tableNameMethod recognizes "func (T) TableName() string { return "table" }".
+func tableNameMethod(d *ast.FuncDecl) (structName, table string, ok bool) {
+	if d.Name.Name != "TableName" || d.Recv == nil || len(d.Recv.List) != 1 || d.Body == nil || len(d.Body.List) != 1 {
+		return "", "", false
+	}
+	recv := d.Recv.List[0].Type
+	if star, isStar := recv.(*ast.StarExpr); isStar {
+		recv = star.X
+	}
+	ident, isIdent := recv.(*ast.Ident)
+	ret, isReturn := d.Body.List[0].(*ast.ReturnStmt)
+	if !isIdent || !isReturn || len(ret.Results) != 1 {
+		return "", "", false
+	}
+	lit, isLit := ret.Results[0].(*ast.BasicLit)
+	if !isLit || lit.Kind != token.STRING {
+		return "", "", false
+	}
+	table, err := strconv.Unquote(lit.Value)
+	return ident.Name, table, err == nil
+}
+
+// associationTarget returns the struct an association field refers to, and whether it
+// holds many of them.
+func associationTarget(expr ast.Expr, structs map[string]*ast.StructType) (string, bool) {
+	collection := false
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		expr = t.X
+	case *ast.ArrayType:
+		expr, collection = t.Elt, true
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+		}
+	}
+	if ident, ok := expr.(*ast.Ident); ok && structs[ident.Name] != nil {
+		return ident.Name, collection
+	}
+	return "", false
+}
+
+// modelColumnOf derives the column of a field the way gorm does on PostgreSQL.
+func modelColumnOf(field string, expr ast.Expr, settings map[string]string, named map[string]ast.Expr) (modelColumn, error) {
+	column := modelColumn{Name: settings["column"], Type: settings["type"]}
+	if column.Name == "" {
+		column.Name = snakeCase(field)
+	}
+	_, notNull := settings["not null"]
+	column.Nullable = !notNull
+	column.Default = settings["default"]
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if column.Type != "" {
+		return column, nil
+	}
+
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			column.Type = "text"
+		case "int", "int64", "uint", "uint64", "uint32":
+			column.Type = "bigint"
+		case "int32", "int16", "int8", "uint16", "uint8":
+			column.Type = "integer"
+		case "float64", "float32":
+			column.Type = "double precision"
+		case "bool":
+			column.Type = "boolean"
+		default:
+			// Named types declared in the package, such as generated enums.
+			if underlying, ok := named[t.Name]; ok {
+				return modelColumnOf(field, underlying, settings, nil)
+			}
+		}
+	case *ast.SelectorExpr:
+		switch fmt.Sprintf("%s.%s", t.X, t.Sel.Name) {
+		case "time.Time":
+			column.Type = "timestamptz"
+		case "decimal.Decimal":
+			column.Type = "numeric"
+		case "json.RawMessage":
+			column.Type = "jsonb"
+		}
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			column.Type = "bytea"
+		}
+	}
+	if column.Type == "" {
+		return column, fmt.Errorf("cannot map its Go type to a column type; add a gorm type setting such as `gorm:\"type:text\"`")
+	}
+	return column, nil
+}
+
+// sqlTypeAliases maps PostgreSQL type names to the spelling used in generated migrations.
+var sqlTypeAliases = map[string]string{
+	"timestamp with time zone":    "timestamptz",
+	"timestamp without time zone": "timestamp",
+	"int8":                        "bigint",
+	"int4":                        "integer",
+	"int":                         "integer",
+	"int2":                        "smallint",
+	"bool":                        "boolean",
+	"float8":                      "double precision",
+	"decimal":                     "numeric",
+	"character varying":           "varchar",
+	"character":                   "char",
+}
+
+var sqlTypeModifier = regexp.MustCompile(`^([a-z0-9 ]+?)\s*(\(.*\))?$`)
+
+// canonicalSQLType normalizes a column type so that the catalog's "numeric(20,4)" or
+// "timestamp with time zone" compare equal to the types of gorm tags.
+func canonicalSQLType(sqlType string) string {
+	t := strings.Join(strings.Fields(strings.ToLower(sqlType)), " ")
+	match := sqlTypeModifier.FindStringSubmatch(t)
+	if match == nil {
+		return t
+	}
+	base := match[1]
+	if alias, ok := sqlTypeAliases[base]; ok {
+		base = alias
+	}
+	return base + strings.ReplaceAll(match[2], " ", "")
+}
+
+// addColumn returns the statements adding a column, defined by def, to a table that may
+// already hold rows. PostgreSQL rejects a NOT NULL column without a default there, so one
+// fills the existing rows: the default declared on the field, which the column keeps, or
+// else the zero value of its type, which is dropped right after.
+func addColumn(table, def, name, sqlType string, nullable bool, declared string) []string {
+	table, name = quoteIdent(table), quoteIdent(name)
+	if nullable {
+		return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, def)}
+	}
+	if declared != "" {
+		return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s DEFAULT %s", table, def, declared)}
+	}
+	return []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s DEFAULT %s", table, def, zeroSQLValue(sqlType)),
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT", table, name),
+	}
+}
+
+// zeroSQLValue is the literal of the zero value of a column type, matching the Go zero
+// value of the field it maps from where there is one.
+func zeroSQLValue(sqlType string) string {
+	base := canonicalSQLType(sqlType)
+	if i := strings.Index(base, "("); i >= 0 {
+		base = base[:i]
+	}
+	switch base {
+	case "bigint", "integer", "smallint", "numeric", "double precision", "real":
+		return "0"
+	case "boolean":
+		return "false"
+	case "uuid":
+		return "'00000000-0000-0000-0000-000000000000'"
+	case "timestamptz", "timestamp", "date":
+		return "'0001-01-01 00:00:00+00'"
+	case "jsonb", "json":
+		return "'null'"
+	}
+	return "''"
+}
+
+// diffSchema compares the entity tables with the database and returns the statements of
+// a migration bringing the database in line with them. Tables missing from the database
+// are created; existing ones get their columns, types, nullability and indexes altered.
+func diffSchema(models []modelTable, db map[string]*dbTable) migrationData {
+	var data migrationData
+	var down []string // Built in apply order and reversed at the end
+	// undo records statements that revert a change together, in the order they run.
+	undo := func(stmts ...string) {
+		for i := len(stmts) - 1; i >= 0; i-- {
+			down = append(down, stmts[i])
+		}
+	}
+	for _, m := range models {
+		current, exists := db[m.Name]
+		if !exists {
+			table := sqlTable{Name: m.Name}
+			for _, c := range m.Columns {
+				def := c.Definition()
+				if c.Name == "id" && c.Type == "uuid" {
+					def = primaryKeyColumn
+				}
+				table.Columns = append(table.Columns, def)
+			}
+			for _, i := range m.Indexes {
+				table.Indexes = append(table.Indexes, i.Statement(m.Name))
+			}
+			data.Tables = append(data.Tables, table)
+			continue
+		}
+
+		table := quoteIdent(m.Name)
+		columns := map[string]dbColumn{}
+		for _, c := range current.Columns {
+			columns[c.Name] = c
+		}
+		declared := map[string]bool{}
+		for _, c := range m.Columns {
+			declared[c.Name] = true
+			column := quoteIdent(c.Name)
+			existing, ok := columns[c.Name]
+			if !ok {
+				data.Up = append(data.Up, addColumn(m.Name, c.Definition(), c.Name, c.Type, c.Nullable, c.Default)...)
+				down = append(down, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column))
+				continue
+			}
+			if canonicalSQLType(existing.Type) != canonicalSQLType(c.Type) {
+				data.Up = append(data.Up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", table, column, c.Type, column, c.Type))
+				down = append(down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", table, column, existing.Type, column, existing.Type))
+			}
+			if existing.Nullable && !c.Nullable {
+				data.Up = append(data.Up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column))
+				down = append(down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, column))
+			} else if !existing.Nullable && c.Nullable {
+				data.Up = append(data.Up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, column))
+				down = append(down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column))
+			}
+		}
+		for _, c := range current.Columns {
+			if declared[c.Name] {
+				continue
+			}
+			def := quoteIdent(c.Name) + " " + c.Type
+			if !c.Nullable {
+				def += " NOT NULL" // Reverting restores the column, but not its data.
+			}
+			data.Up = append(data.Up, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, quoteIdent(c.Name)))
+			undo(addColumn(m.Name, def, c.Name, c.Type, c.Nullable, "")...)
+		}
+
+		indexes := map[string]dbIndex{}
+		for _, i := range current.Indexes {
+			indexes[i.Name] = i
+		}
+		wantIndex := map[string]bool{}
+		for _, i := range m.Indexes {
+			wantIndex[i.Name] = true
+			if _, ok := indexes[i.Name]; !ok {
+				data.Up = append(data.Up, i.Statement(m.Name))
+				down = append(down, "DROP INDEX IF EXISTS "+quoteIdent(i.Name))
+			}
+		}
+		// Only indexes following gorm's naming are managed; others were added by hand.
+		for _, i := range current.Indexes {
+			if strings.HasPrefix(i.Name, "idx_"+m.Name+"_") && !wantIndex[i.Name] {
+				data.Up = append(data.Up, "DROP INDEX IF EXISTS "+quoteIdent(i.Name))
+				down = append(down, i.Definition)
+			}
+		}
+	}
+
+	for _, m := range models {
+		for _, j := range m.Joins {
+			if _, exists := db[j.Name]; exists {
+				continue
+			}
+			data.Tables = append(data.Tables, sqlTable{Name: j.Name, Columns: []string{
+				fmt.Sprintf("%s uuid NOT NULL REFERENCES %s ON DELETE CASCADE", quoteIdent(j.OwnerColumn), referencedID(j.OwnerTable)),
+				fmt.Sprintf("%s uuid NOT NULL REFERENCES %s ON DELETE CASCADE", quoteIdent(j.RefColumn), referencedID(j.TargetTable)),
+				fmt.Sprintf("PRIMARY KEY (%s, %s)", quoteIdent(j.OwnerColumn), quoteIdent(j.RefColumn)),
+			}})
+		}
+	}
+
+	for i := len(down) - 1; i >= 0; i-- {
+		data.Down = append(data.Down, down[i])
+	}
+	return data
+}
+
+// inspectDatabase runs the migration runner's inspect command for a service.
+func inspectDatabase(serviceName string) (*dbSchema, error) {
+	report, err := os.CreateTemp("", "gores-schema-*.json")
+	if err != nil {
+		return nil, err
+	}
+	report.Close()
+	defer os.Remove(report.Name())
+
+	if err := os.MkdirAll(migrationsDir(serviceName), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create %s folder: %w", filepath.ToSlash(migrationsDir(serviceName)), err)
+	}
+	dir, err := filepath.Abs(migrationsDir(serviceName))
+	if err != nil {
+		return nil, err
+	}
+	runner := exec.Command("go", "run", "./"+filepath.ToSlash(migrationRunner), "-service", serviceName, "-dir", dir, "-out", report.Name(), "inspect")
+	runner.Dir = pkgModuleDir
+	// The runner prints its connection diagnostics; only failures are of interest here.
+	output, err := runner.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect the database:\n%s", output)
+	}
+
+	content, err := os.ReadFile(report.Name())
+	if err != nil {
+		return nil, err
+	}
+	var schema dbSchema
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return nil, fmt.Errorf("failed to read the database schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// migrationDiffCmd is the Cobra command for generating a migration from entity changes.
+var migrationDiffCmd = &cobra.Command{
+	Use:   "diff [service-name] [migration-name]",
+	Short: "Generate the migration that brings the database in line with the service's entities",
+	Long: "Introspects the database configured by the POSTGRES_* variables, compares it with the gorm-tagged entity structs " +
+		"of the service in pkg/entities and writes the ALTER TABLE statements (and their reversal) as a new migration. " +
+		"The migration name defaults to update_<service>.",
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --- Prerequisite Check ---
+		manifest, err := loadProjectManifest()
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(pkgModuleDir, migrationRunner, "main.go")); err != nil {
+			return fmt.Errorf("the migration runner %s is missing; run 'gores upgrade pkg' to add it",
+				filepath.ToSlash(filepath.Join(pkgModuleDir, migrationRunner)))
+		}
+		// --- End Prerequisite Check ---
+
+		serviceName := args[0]
+		entry := manifest.Service(serviceName)
+		if entry == nil {
+			return fmt.Errorf("service '%s' is not registered in %s", serviceName, ManifestFile)
+		}
+		if err := checkVersionedMigrations(manifest, entry); err != nil {
+			return err
+		}
+		name := "update_" + snakeCase(serviceName)
+		if len(args) == 2 {
+			name = snakeCase(args[1])
+		}
+		if !migrationNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid migration name '%s': use letters, digits, '-' or '_', starting with a letter", name)
+		}
+
+		models, err := parseEntityModels(filepath.Join("pkg", "entities"), serviceEntityFiles(entry))
+		if err != nil {
+			return err
+		}
+		schema, err := inspectDatabase(serviceName)
+		if err != nil {
+			return err
+		}
+		if len(schema.Pending) > 0 {
+			return fmt.Errorf("service '%s' has pending migrations (%s); apply them with 'gores migrate up %s' first, "+
+				"or the diff would repeat them", serviceName, strings.Join(schema.Pending, ", "), serviceName)
+		}
+
+		data := diffSchema(models, schema.Tables)
+		if len(data.Tables) == 0 && len(data.Up) == 0 {
+			fmt.Printf("The database matches the entities of service '%s'; no migration needed.\n", serviceName)
+			return nil
+		}
+		existing, _ := filepath.Glob(filepath.Join(migrationsDir(serviceName), "*.up.sql"))
+		data.Service, data.Name = serviceName, name
+		data.Version = nextMigrationVersion(existing, time.Now().UTC())
+
+		if migrationDiffDryRun {
+			mem := newMemFS()
+			files, err := writeMigration(mem, data)
+			if err != nil {
+				return err
+			}
+			for _, direction := range []string{"up", "down"} {
+				for file := range files {
+					if strings.HasSuffix(file, "."+direction+".sql") {
+						content, _ := mem.ReadFile(file)
+						fmt.Printf("--- %s\n%s\n", filepath.ToSlash(file), content)
+					}
+				}
+			}
+			return nil
+		}
+		if _, err := writeMigration(osFS{}, data); err != nil {
+			return err
+		}
+		fmt.Printf("Review the migration, then run 'gores migrate up %s'.\n", serviceName)
+		return nil
+	},
+}
+
+func init() {
+	migrationDiffCmd.Flags().BoolVar(&migrationDiffDryRun, "dry-run", false, "Print the migration instead of writing it")
+	migrationCmd.AddCommand(migrationDiffCmd)
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// schemaModels generates the golden schema service and parses its entity structs.
+func schemaModels(t *testing.T) []modelTable {
+	t.Helper()
+	chdir(t, t.TempDir())
+	_, entities, err := loadSchemaFile(goldenSchemaPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createSharedPkg(osFS{}, generatorOptions{Module: defaultModulePath}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createAuthMicroservice(osFS{}, generatorOptions{Module: defaultModulePath}, authServiceName, "8080"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createMicroservice(osFS{}, generatorOptions{Module: defaultModulePath}, "orders", "8081", "templates/", entities); err != nil {
+		t.Fatal(err)
+	}
+	entry := &ServiceEntry{Name: "orders", Template: TemplateGeneric, Entities: make([]SchemaEntity, len(entities))}
+	for i, e := range entities {
+		entry.Entities[i] = canonicalSchemaEntity(e)
+	}
+	models, err := parseEntityModels(filepath.Join("pkg", "entities"), serviceEntityFiles(entry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models
+}
+
+func TestParseEntityModelsMatchesMigrations(t *testing.T) {
+	models := schemaModels(t)
+	_, entities, err := loadSchemaFile(goldenSchemaPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tables of the structs agree with the ones the first migration creates, so a
+	// diff right after it is empty.
+	db := map[string]*dbTable{}
+	for _, table := range sqlTables(entities) {
+		db[table.Name] = &dbTable{}
+	}
+	for _, m := range models {
+		for _, c := range m.Columns {
+			db[m.Name].Columns = append(db[m.Name].Columns, dbColumn{Name: c.Name, Type: c.Type, Nullable: c.Nullable})
+		}
+		for _, i := range m.Indexes {
+			db[m.Name].Indexes = append(db[m.Name].Indexes, dbIndex{Name: i.Name})
+		}
+	}
+	for _, table := range sqlTables(entities) {
+		if len(db[table.Name].Columns) > 0 && len(db[table.Name].Columns) != len(table.Columns) {
+			t.Errorf("%s: the struct has %d columns, the migration %d", table.Name, len(db[table.Name].Columns), len(table.Columns))
+		}
+	}
+	if data := diffSchema(models, db); len(data.Tables)+len(data.Up) > 0 {
+		t.Errorf("diff against an up-to-date database = %+v", data)
+	}
+
+	orders := models[0]
+	if orders.Name != "orders" || orders.Columns[3] != (modelColumn{Name: "customer_id", Type: "uuid", Nullable: true}) {
+		t.Errorf("orders = %+v", orders)
+	}
+	if want := []modelJoin{{"orders_tags", "orders_id", "tags_id", "orders", "tags"}}; !reflect.DeepEqual(orders.Joins, want) {
+		t.Errorf("orders joins = %+v", orders.Joins)
+	}
+	if got := models[1].Columns[4].Definition(); got != `"order_id" uuid NOT NULL REFERENCES "orders" ("id")` {
+		t.Errorf("line_items.order_id = %q", got)
+	}
+
+	users, err := parseEntityModels(filepath.Join("pkg", "entities"), []string{entityFilePath(authServiceName, TemplateAuth)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range users[0].Columns {
+		got = append(got, c.Definition())
+	}
+	want := sqlTables([]EntitySpec{authUserEntity()})[0].Columns
+	want[0] = `"id" uuid NOT NULL` // The primary key is spelled differently, but is the same column.
+	if users[0].Name != "users" || !reflect.DeepEqual(got, want) {
+		t.Errorf("users columns = %q, want %q", got, want)
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	models := []modelTable{{
+		Name: "orders",
+		Columns: []modelColumn{
+			{Name: "id", Type: "uuid"},
+			{Name: "total", Type: "numeric(20,4)"},
+			{Name: "note", Type: "text", Nullable: true},
+			{Name: "quantity", Type: "bigint"},
+			{Name: "status", Type: "text", Default: "'new'"},
+			{Name: "created_at", Type: "timestamptz", Nullable: true},
+			{Name: "user", Type: "text", Nullable: true}, // A reserved word, usable once quoted
+		},
+		Indexes: []modelIndex{{Name: "idx_orders_note", Column: "note"}, {Name: "idx_orders_user", Column: "user", Unique: true}},
+	}, {
+		Name:    "refunds",
+		Columns: []modelColumn{{Name: "id", Type: "uuid"}, {Name: "order_id", Type: "uuid", References: "orders"}},
+	}}
+	db := map[string]*dbTable{"orders": {
+		Columns: []dbColumn{
+			{Name: "id", Type: "uuid"},
+			{Name: "total", Type: "numeric(10, 2)", Nullable: true},
+			{Name: "created_at", Type: "timestamp with time zone", Nullable: true},
+			{Name: "legacy", Type: "character varying(20)"},
+		},
+		Indexes: []dbIndex{
+			{Name: "orders_pkey"},
+			{Name: "idx_orders_legacy", Definition: "CREATE INDEX idx_orders_legacy ON public.orders USING btree (legacy)"},
+		},
+	}}
+
+	data := diffSchema(models, db)
+	wantUp := []string{
+		`ALTER TABLE "orders" ALTER COLUMN "total" TYPE numeric(20,4) USING "total"::numeric(20,4)`,
+		`ALTER TABLE "orders" ALTER COLUMN "total" SET NOT NULL`,
+		`ALTER TABLE "orders" ADD COLUMN "note" text`,
+		// Existing rows get the zero value of a new NOT NULL column, or its declared default.
+		`ALTER TABLE "orders" ADD COLUMN "quantity" bigint NOT NULL DEFAULT 0`,
+		`ALTER TABLE "orders" ALTER COLUMN "quantity" DROP DEFAULT`,
+		`ALTER TABLE "orders" ADD COLUMN "status" text NOT NULL DEFAULT 'new'`,
+		`ALTER TABLE "orders" ADD COLUMN "user" text`,
+		`ALTER TABLE "orders" DROP COLUMN "legacy"`,
+		`CREATE INDEX "idx_orders_note" ON "orders" ("note")`,
+		`CREATE UNIQUE INDEX "idx_orders_user" ON "orders" ("user")`,
+		`DROP INDEX IF EXISTS "idx_orders_legacy"`,
+	}
+	wantDown := []string{
+		"CREATE INDEX idx_orders_legacy ON public.orders USING btree (legacy)",
+		`DROP INDEX IF EXISTS "idx_orders_user"`,
+		`DROP INDEX IF EXISTS "idx_orders_note"`,
+		`ALTER TABLE "orders" ADD COLUMN "legacy" character varying(20) NOT NULL DEFAULT ''`,
+		`ALTER TABLE "orders" ALTER COLUMN "legacy" DROP DEFAULT`,
+		`ALTER TABLE "orders" DROP COLUMN "user"`,
+		`ALTER TABLE "orders" DROP COLUMN "status"`,
+		`ALTER TABLE "orders" DROP COLUMN "quantity"`,
+		`ALTER TABLE "orders" DROP COLUMN "note"`,
+		`ALTER TABLE "orders" ALTER COLUMN "total" DROP NOT NULL`,
+		`ALTER TABLE "orders" ALTER COLUMN "total" TYPE numeric(10, 2) USING "total"::numeric(10, 2)`,
+	}
+	if !reflect.DeepEqual(data.Up, wantUp) {
+		t.Errorf("up = %#v", data.Up)
+	}
+	if !reflect.DeepEqual(data.Down, wantDown) {
+		t.Errorf("down = %#v", data.Down)
+	}
+	wantTables := []sqlTable{{Name: "refunds", Columns: []string{`"id" uuid PRIMARY KEY DEFAULT gen_random_uuid()`, `"order_id" uuid NOT NULL REFERENCES "orders" ("id")`}}}
+	if !reflect.DeepEqual(data.Tables, wantTables) {
+		t.Errorf("tables = %+v", data.Tables)
+	}
+}
+
+func TestZeroSQLValue(t *testing.T) {
+	for sqlType, want := range map[string]string{
+		"bigint":                   "0",
+		"numeric(20,4)":            "0",
+		"boolean":                  "false",
+		"uuid":                     "'00000000-0000-0000-0000-000000000000'",
+		"timestamp with time zone": "'0001-01-01 00:00:00+00'",
+		"jsonb":                    "'null'",
+		"character varying(20)":    "''",
+		"text":                     "''",
+	} {
+		if got := zeroSQLValue(sqlType); got != want {
+			t.Errorf("zeroSQLValue(%q) = %s, want %s", sqlType, got, want)
+		}
+	}
+}
+
+func TestCanonicalSQLType(t *testing.T) {
+	for in, want := range map[string]string{
+		"timestamp with time zone": "timestamptz",
+		"numeric(20, 4)":           "numeric(20,4)",
+		"character varying(255)":   "varchar(255)",
+		"INT8":                     "bigint",
+		"jsonb":                    "jsonb",
+	} {
+		if got := canonicalSQLType(in); got != want {
+			t.Errorf("canonicalSQLType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
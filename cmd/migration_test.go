@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/mod/modfile"
+)
+
+func TestEntityTable(t *testing.T) {
+	for name, want := range map[string]string{
+		"orders":     "orders",
+		"line-items": "line_items",
+		"orderItem":  "order_items",
+		"ORDERS":     "orders",
+		"category":   "categories",
+		"day":        "days",
+		"box":        "boxes",
+		"batch":      "batches",
+		"user":       "users",
+	} {
+		if got := newEntitySpec(name, nil).Table(); got != want {
+			t.Errorf("Table() of %q = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSQLTables(t *testing.T) {
+	// line-items is declared first but references orders, so it is created after it.
+	entities := relatedEntities(t, map[string][]string{
+		"orders":     {"customer:belongs-to:user", "items:has-many:line-items"},
+		"line-items": {"order:belongs-to:orders", "tags:many-to-many:tags"},
+	})
+	entities[0], entities[1] = entities[1], entities[0]
+	if err := linkEntities(entities); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := sqlTables(entities)
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"orders", "line_items", "line_items_tags"}) {
+		t.Fatalf("tables = %v", names)
+	}
+	if got := tables[0].Columns[1]; got != `"customer_id" uuid NOT NULL` {
+		t.Errorf("a foreign key to another service's table got a constraint: %q", got)
+	}
+	if got := tables[1].Columns[1]; got != `"order_id" uuid NOT NULL REFERENCES "orders" ("id")` {
+		t.Errorf("order_id column = %q", got)
+	}
+	if got := strings.Join(tables[2].Columns, ", "); got != `"line_items_id" uuid NOT NULL REFERENCES "line_items" ("id") ON DELETE CASCADE, `+
+		`"tags_id" uuid NOT NULL, PRIMARY KEY ("line_items_id", "tags_id")` {
+		t.Errorf("join table columns = %q", got)
+	}
+	if got := tables[1].Indexes; !reflect.DeepEqual(got, []string{`CREATE INDEX "idx_line_items_order_id" ON "line_items" ("order_id")`}) {
+		t.Errorf("line_items indexes = %v", got)
+	}
+
+	// Fields named after SQL reserved words are quoted wherever they appear.
+	fields, err := parseFieldSpecs("Shops", []string{"order:int:required", "user:string:unique"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := sqlTables([]EntitySpec{newEntitySpec("shops", fields)})[0]
+	if got := table.Columns[1:3]; !reflect.DeepEqual(got, []string{`"order" bigint NOT NULL`, `"user" text`}) {
+		t.Errorf("shops columns = %q", got)
+	}
+	if got := table.Indexes; !reflect.DeepEqual(got, []string{`CREATE UNIQUE INDEX "idx_shops_user" ON "shops" ("user")`}) {
+		t.Errorf("shops indexes = %q", got)
+	}
+}
+
+func TestAuthUserEntityMatchesModel(t *testing.T) {
+	table := sqlTables([]EntitySpec{authUserEntity()})[0]
+	want := []string{
+		`"id" uuid PRIMARY KEY DEFAULT gen_random_uuid()`,
+		`"email" text NOT NULL`,
+		`"password_hash" text NOT NULL`,
+		`"name" text`,
+		`"created_at" timestamptz`,
+		`"updated_at" timestamptz`,
+	}
+	if table.Name != "users" || !reflect.DeepEqual(table.Columns, want) {
+		t.Errorf("users table = %+v", table)
+	}
+}
+
+func TestNextMigrationVersion(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 500, time.UTC)
+	if got := nextMigrationVersion(nil, now); got != "20240102150405" {
+		t.Errorf("first version = %s", got)
+	}
+	existing := []string{"migrations/20240102150405_create_orders.up.sql", "migrations/20240101000000_init.up.sql"}
+	if got := nextMigrationVersion(existing, now); got != "20240102150406" {
+		t.Errorf("version after a migration of the same second = %s", got)
+	}
+}
+
+// migrateSQLiteTest runs the rendered migration runner against SQLite.
+const migrateSQLiteTest = `package migrate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+var migrations = fstest.MapFS{
+	"20240101000000_create_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id TEXT PRIMARY KEY);")},
+	"20240101000000_create_orders.down.sql": {Data: []byte("DROP TABLE orders;")},
+	"20240102000000_add_total.up.sql":       {Data: []byte("ALTER TABLE orders ADD COLUMN total TEXT;")},
+	"20240102000000_add_total.down.sql":     {Data: []byte("ALTER TABLE orders DROP COLUMN total;")},
+}
+
+func TestMigrator(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	m, err := New(db, "orders", migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if done, err := m.Up(ctx); err != nil || len(done) != 2 {
+		t.Fatalf("Up() = %v, %v, want both migrations", done, err)
+	}
+	if done, err := m.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("second Up() = %v, %v, want nothing", done, err)
+	}
+	if _, err := db.Exec("INSERT INTO orders (id, total) VALUES ('a', '1.50')"); err != nil {
+		t.Fatalf("the migrated table lacks a column: %v", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil || len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[1].AppliedAt == nil {
+		t.Fatalf("Status() = %+v, %v, want both applied", statuses, err)
+	}
+
+	done, err := m.Down(ctx, 1)
+	if err != nil || len(done) != 1 || done[0].ID() != "20240102000000_add_total" {
+		t.Fatalf("Down(1) = %v, %v, want the latest migration", done, err)
+	}
+	if _, err := db.Exec("INSERT INTO orders (id, total) VALUES ('b', '2')"); err == nil {
+		t.Error("the reverted column still exists")
+	}
+	statuses, err = m.Status(ctx)
+	if err != nil || statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Fatalf("Status() after Down = %+v, %v, want the latest pending", statuses, err)
+	}
+
+	// Another service sharing the database keeps its own rows.
+	other, err := New(db, "payments", fstest.MapFS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses, err := other.Status(ctx); err != nil || len(statuses) != 0 {
+		t.Errorf("Status() of another service = %+v, %v", statuses, err)
+	}
+}
+
+func TestNewRejectsMissingDown(t *testing.T) {
+	_, err := New(nil, "orders", fstest.MapFS{
+		"20240101000000_create_orders.up.sql": {Data: []byte("CREATE TABLE orders (id TEXT);")},
+	})
+	if err == nil {
+		t.Fatal("New accepted a migration without a .down.sql file")
+	}
+}
+`
+
+func TestMigrateRunsOnSQLite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a module with the SQLite driver")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go tool is not installed")
+	}
+
+	// The module requires the SQLite driver and GORM at the versions pinned for --db sqlite.
+	data := newTemplateData(generatorOptions{Module: defaultModulePath}, "", "")
+	data.Databases = []string{"sqlite"}
+	pkgGoMod, err := renderTemplate("templates/pkg_go.mod.tmpl", "go.mod", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := modfile.Parse("go.mod", pkgGoMod, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goMod := &modfile.File{}
+	goMod.AddModuleStmt("migrate")
+	goMod.AddGoStmt(pinned.Go.Version)
+	for _, req := range pinned.Require {
+		if req.Mod.Path == "github.com/glebarez/sqlite" || req.Mod.Path == "gorm.io/gorm" {
+			goMod.AddNewRequire(req.Mod.Path, req.Mod.Version, false)
+		}
+	}
+	goModContent, err := goMod.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrate, err := renderTemplate("templates/migrate.tmpl", "migrate.go", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"go.mod":          goModContent,
+		"migrate.go":      migrate,
+		"migrate_test.go": []byte(migrateSQLiteTest),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) ([]byte, error) {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+		return cmd.CombinedOutput()
+	}
+	if out, err := run("mod", "tidy"); err != nil {
+		t.Skipf("cannot fetch the SQLite driver: %v\n%s", err, out)
+	}
+	if out, err := run("test", "./..."); err != nil {
+		t.Fatalf("the rendered migration runner fails against SQLite: %v\n%s", err, out)
+	}
+}
@@ -1,140 +1,119 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"net"
-	"os"             // Changed from "io/ioutil" to "os" for file operations
-	"path/filepath"  // Import filepath for ServiceExists
-	"strconv"
+	"os"
+	"path/filepath" // Import filepath for ServiceExists
 )
 
-// PortInfo represents a single entry in the used ports file.
-type PortInfo struct {
-	Port    int    `json:"port"`
-	Service string `json:"service"`
-}
-
-// UsedPorts represents the entire used ports file content.
-type UsedPorts struct {
-	Ports []PortInfo `json:"used_ports"`
-}
+const maxPort = 65535
 
 // ServiceExists checks if a directory for the service already exists.
 // It assumes the services are directly under the 'services' directory in the root.
 func ServiceExists(serviceName string) bool { // Exported
-	servicePath := filepath.Join("services", serviceName) // Corrected path to services directory
+	servicePath := filepath.Join(servicesDir, serviceName)
 	_, err := os.Stat(servicePath)
 	return !os.IsNotExist(err)
 }
 
-// ReadUsedPorts reads the used ports from a JSON file.
-func ReadUsedPorts(filename string) (*UsedPorts, error) { // Exported
-	data := &UsedPorts{}
-
-	content, err := os.ReadFile(filename) // Using os.ReadFile
-	if err != nil {
-		if os.IsNotExist(err) {
-			return data, nil
-		}
-		return nil, fmt.Errorf("failed to read used ports file '%s': %w", filename, err)
-	}
-
-	err = json.Unmarshal(content, data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal used ports JSON from '%s': %w", filename, err)
-	}
-	return data, nil
-}
-
-// WriteUsedPorts writes the used ports data to a JSON file.
-func WriteUsedPorts(filename string, data *UsedPorts) error { // Exported
-	bytes, err := json.MarshalIndent(data, "", "    ") // 4 spaces for indent
+// isPortFree reports whether the port can currently be bound at the OS level.
+func isPortFree(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
-		return fmt.Errorf("failed to marshal used ports data: %w", err)
-	}
-	return os.WriteFile(filename, bytes, 0644) // Using os.WriteFile
-}
-
-// IsPortUsed checks if a port is in the UsedPorts slice.
-func IsPortUsed(used *UsedPorts, port int) bool { // Exported
-	for _, p := range used.Ports {
-		if p.Port == port {
-			return true
-		}
+		return false
 	}
-	return false
+	l.Close()
+	return true
 }
 
 // GetNextAvailablePort finds the next available port, skipping ports that are
-// explicitly marked as used or are currently occupied at the OS level.
-func GetNextAvailablePort(start int, used *UsedPorts) (int, error) { // Exported
-	const maxPort = 65535
+// assigned in the manifest or are currently occupied at the OS level.
+func GetNextAvailablePort(start int, m *Manifest) (int, error) { // Exported
 	for port := start; port <= maxPort; port++ {
-		if IsPortUsed(used, port) {
+		if m.IsPortUsed(port) {
 			continue
 		}
-
-		addr := fmt.Sprintf(":%d", port)
-		l, err := net.Listen("tcp", addr)
-		if err != nil {
+		if !isPortFree(port) {
 			continue
 		}
-		l.Close()
 		return port, nil
 	}
 	return 0, fmt.Errorf("no available port found starting at %d up to %d", start, maxPort)
 }
 
-// ReadAndIncrementPortWithUsed finds the next available port, records it, and increments the next start port.
-// This is used for automatic port assignment.
-func ReadAndIncrementPortWithUsed(start int, serviceName, nextPortFile, usedPortsFile string) (int, error) { // Exported
-	nextPort := start
-	portBytes, err := os.ReadFile(nextPortFile) // Using os.ReadFile
-	if err == nil {
-		p, err := strconv.Atoi(string(portBytes))
-		if err == nil && p >= start {
-			nextPort = p
-		}
+// AllocatePort picks the next free port for automatic assignment and advances the
+// manifest's next-port setting past it. The caller records the port with AddService.
+func AllocatePort(m *Manifest) (int, error) { // Exported
+	start := m.Settings.NextPort
+	if start < m.Settings.BasePort {
+		start = m.Settings.BasePort
 	}
 
-	usedPorts, err := ReadUsedPorts(usedPortsFile)
+	port, err := GetNextAvailablePort(start, m)
 	if err != nil {
 		return 0, err
 	}
+	m.Settings.NextPort = port + 1
+	return port, nil
+}
 
-	port, err := GetNextAvailablePort(nextPort, usedPorts)
-	if err != nil {
-		return 0, err
+// CheckPortAvailable verifies that a user-provided port is neither assigned in the
+// manifest nor in use by another process.
+func CheckPortAvailable(m *Manifest, port int) error { // Exported
+	if owner := m.PortOwner(port); owner != "" {
+		return fmt.Errorf("the port '%d' is already assigned to service '%s'", port, owner)
 	}
-
-	usedPorts.Ports = append(usedPorts.Ports, PortInfo{Port: port, Service: serviceName})
-
-	err = WriteUsedPorts(usedPortsFile, usedPorts)
-	if err != nil {
-		return 0, fmt.Errorf("failed to write updated used ports file: %w", err)
+	if !isPortFree(port) {
+		return fmt.Errorf("the port '%d' is currently in use by another process on your system", port)
 	}
+	return nil
+}
 
-	err = os.WriteFile(nextPortFile, []byte(strconv.Itoa(port+1)), 0644) // Using os.WriteFile
+// ReserveService registers a service in the manifest at manifestPath while holding the
+// project lock, so concurrent gores runs never hand out the same port. If requestedPort
+// is 0 the next available port is assigned. Call ReleaseService to undo the reservation
+// when generation fails.
+func ReserveService(manifestPath, name string, requestedPort int, template string) (ServiceEntry, error) { // Exported
+	var entry ServiceEntry
+	_, err := UpdateManifest(manifestPath, func(m *Manifest) error {
+		var err error
+		entry, err = reserveInManifest(m, name, requestedPort, template)
+		return err
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to update next port file: %w", err)
+		return ServiceEntry{}, err
 	}
-
-	return port, nil
+	return entry, nil
 }
 
-// WriteUsedPortForService adds a user-provided port and service to the used ports file.
-// This is used when a user explicitly specifies a port.
-func WriteUsedPortForService(port int, serviceName, filename string) error { // Exported
-	usedPorts, err := ReadUsedPorts(filename)
-	if err != nil {
-		return err
+// reserveInManifest picks a port for a new service and adds it to m without saving.
+func reserveInManifest(m *Manifest, name string, requestedPort int, template string) (ServiceEntry, error) {
+	if m.Service(name) != nil {
+		return ServiceEntry{}, fmt.Errorf("a service with the name '%s' is already registered in %s", name, ManifestFile)
 	}
 
-	if !IsPortUsed(usedPorts, port) {
-		usedPorts.Ports = append(usedPorts.Ports, PortInfo{Port: port, Service: serviceName})
-		return WriteUsedPorts(filename, usedPorts)
+	port := requestedPort
+	if port == 0 {
+		p, err := AllocatePort(m)
+		if err != nil {
+			return ServiceEntry{}, fmt.Errorf("failed to get next available port: %w", err)
+		}
+		port = p
+	} else if err := CheckPortAvailable(m, port); err != nil {
+		return ServiceEntry{}, err
 	}
 
-	return nil
-}
\ No newline at end of file
+	entry := newServiceEntry(name, port, template)
+	return entry, m.AddService(entry)
+}
+
+// ReleaseService removes a service and frees its port in the manifest at manifestPath.
+// Releasing a service that is not registered is not an error.
+func ReleaseService(manifestPath, name string) error { // Exported
+	_, err := UpdateManifest(manifestPath, func(m *Manifest) error {
+		m.RemoveService(name)
+		return nil
+	})
+	return err
+}
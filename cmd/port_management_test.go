@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestManifest writes an empty manifest into a temporary directory and returns its path.
+func newTestManifest(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ManifestFile)
+	m := NewManifest(defaultModulePath)
+	m.Settings.BasePort = 23000
+	m.Settings.NextPort = 23000
+	if err := SaveManifest(path, m); err != nil {
+		t.Fatalf("SaveManifest: %v", err)
+	}
+	return path
+}
+
+// assertUniquePorts loads the manifest and checks it holds want services with distinct ports.
+func assertUniquePorts(t *testing.T, path string, want int) {
+	t.Helper()
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	if len(m.Services) != want {
+		t.Fatalf("manifest has %d services, want %d", len(m.Services), want)
+	}
+	seen := map[int]string{}
+	for _, s := range m.Services {
+		if other, ok := seen[s.Port]; ok {
+			t.Fatalf("port %d allocated to both %q and %q", s.Port, other, s.Name)
+		}
+		seen[s.Port] = s.Name
+	}
+}
+
+func TestReserveServiceConcurrentGoroutines(t *testing.T) {
+	path := newTestManifest(t)
+
+	const workers = 32
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := ReserveService(path, fmt.Sprintf("svc-%d", i), 0, TemplateGeneric); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("ReserveService: %v", err)
+	}
+
+	assertUniquePorts(t, path, workers)
+}
+
+func TestReserveServiceConcurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns child processes")
+	}
+	path := newTestManifest(t)
+
+	const procs, perProc = 6, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, procs)
+	for p := 0; p < procs; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestReserveServiceHelperProcess$")
+			cmd.Env = append(os.Environ(),
+				"GORES_RESERVE_HELPER=1",
+				"GORES_RESERVE_MANIFEST="+path,
+				"GORES_RESERVE_PREFIX="+fmt.Sprintf("proc%d", p),
+				"GORES_RESERVE_COUNT="+strconv.Itoa(perProc),
+			)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("helper %d: %v\n%s", p, err, out)
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	assertUniquePorts(t, path, procs*perProc)
+}
+
+// TestReserveServiceHelperProcess is executed as a child process by
+// TestReserveServiceConcurrentProcesses; it does nothing when run directly.
+func TestReserveServiceHelperProcess(t *testing.T) {
+	if os.Getenv("GORES_RESERVE_HELPER") != "1" {
+		return
+	}
+	path := os.Getenv("GORES_RESERVE_MANIFEST")
+	prefix := os.Getenv("GORES_RESERVE_PREFIX")
+	count, _ := strconv.Atoi(os.Getenv("GORES_RESERVE_COUNT"))
+	for i := 0; i < count; i++ {
+		if _, err := ReserveService(path, fmt.Sprintf("%s-svc-%d", prefix, i), 0, TemplateGeneric); err != nil {
+			t.Fatalf("ReserveService: %v", err)
+		}
+	}
+}
+
+func TestReserveServiceRejectsDuplicates(t *testing.T) {
+	path := newTestManifest(t)
+
+	entry, err := ReserveService(path, "orders", 0, TemplateGeneric)
+	if err != nil {
+		t.Fatalf("ReserveService: %v", err)
+	}
+	if _, err := ReserveService(path, "orders", 0, TemplateGeneric); err == nil {
+		t.Fatal("expected duplicate service name to be rejected")
+	}
+	if _, err := ReserveService(path, "billing", entry.Port, TemplateGeneric); err == nil {
+		t.Fatal("expected duplicate port to be rejected")
+	}
+}
+
+func TestReleaseServiceFreesPort(t *testing.T) {
+	path := newTestManifest(t)
+
+	entry, err := ReserveService(path, "orders", 0, TemplateGeneric)
+	if err != nil {
+		t.Fatalf("ReserveService: %v", err)
+	}
+	if err := ReleaseService(path, "orders"); err != nil {
+		t.Fatalf("ReleaseService: %v", err)
+	}
+
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	if m.Service("orders") != nil || m.IsPortUsed(entry.Port) {
+		t.Fatalf("service still registered after release: %+v", m.Services)
+	}
+	if _, err := ReserveService(path, "billing", entry.Port, TemplateGeneric); err != nil {
+		t.Fatalf("released port %d could not be reused: %v", entry.Port, err)
+	}
+}
+
+func TestWriteFileAtomicLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.yaml")
+	for i := 0; i < 3; i++ {
+		if err := writeFileAtomic(path, []byte(strconv.Itoa(i)), 0644); err != nil {
+			t.Fatalf("writeFileAtomic: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "2" {
+		t.Fatalf("got %q, %v; want \"2\"", content, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected only the target file, found %d entries", len(entries))
+	}
+}
+
+func TestLoadManifestMigratesLegacyStateUnderLock(t *testing.T) {
+	dir := t.TempDir()
+	legacy := map[string]string{
+		legacyUsedPortsFile: `{"used_ports": [{"port": 8080, "service": "auth"}, {"port": 8081, "service": "orders"}]}`,
+		legacyNextPortFile:  "8082",
+	}
+	for name, content := range legacy {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The migration waits for another command holding the project lock.
+	path := filepath.Join(dir, ManifestFile)
+	locked, release := make(chan struct{}), make(chan struct{})
+	go withProjectLock(dir, func() error {
+		close(locked)
+		<-release
+		return nil
+	})
+	<-locked
+	done := make(chan error, 1)
+	go func() {
+		m, err := LoadManifest(path)
+		if err == nil && (m.Service("orders") == nil || m.Service("orders").Port != 8081) {
+			err = fmt.Errorf("migrated manifest lacks orders: %+v", m.Services)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("LoadManifest migrated while the project was locked (err: %v)", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("%s was written while the project was locked (err: %v)", ManifestFile, err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+
+	assertUniquePorts(t, path, 2)
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if e.Name() != ManifestFile && e.Name() != lockFile {
+			t.Errorf("unexpected file %s left after the migration", e.Name())
+		}
+	}
+}
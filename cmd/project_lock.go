@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	// lockFile guards read-modify-write cycles of the manifest across processes.
+	lockFile = ".gores.lock"
+
+	lockTimeout    = 30 * time.Second
+	lockRetryDelay = 25 * time.Millisecond
+)
+
+// withProjectLock runs fn while holding an exclusive advisory lock on the project
+// rooted at dir. Concurrent gores processes (and goroutines) block until the lock is free.
+func withProjectLock(dir string, fn func() error) error {
+	lock := flock.New(filepath.Join(dir, lockFile))
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	locked, err := lock.TryLockContext(ctx, lockRetryDelay)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s waiting for project lock '%s'; is another gores command running?", lockTimeout, lock.Path())
+		}
+		return fmt.Errorf("failed to acquire project lock '%s': %w", lock.Path(), err)
+	}
+	if !locked {
+		return fmt.Errorf("failed to acquire project lock '%s'", lock.Path())
+	}
+	defer lock.Unlock()
+
+	return fn()
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into
+// place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for '%s': %w", path, err)
+	}
+	tmpPath := tmp.Name()
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpPath)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to write temporary file for '%s': %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to sync temporary file for '%s': %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temporary file for '%s': %w", path, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions on temporary file for '%s': %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace '%s': %w", path, err)
+	}
+	return nil
+}
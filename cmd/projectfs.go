@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// projectFS is the filesystem the generators write through. osFS writes to disk; memFS
+// records writes in memory so --dry-run and --diff can report them without touching the tree.
+type projectFS interface {
+	Stat(path string) (os.FileInfo, error)
+	ReadFile(path string) ([]byte, error)
+	MkdirAll(path string, perm os.FileMode) error
+	WriteFile(path string, data []byte, perm os.FileMode) error
+}
+
+// osFS is the projectFS backed by the real filesystem.
+type osFS struct{}
+
+func (osFS) Stat(path string) (os.FileInfo, error)        { return os.Stat(path) }
+func (osFS) ReadFile(path string) ([]byte, error)         { return os.ReadFile(path) }
+func (osFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+// memFS is a projectFS that keeps every write in memory, layered over the real filesystem
+// for reads. The disk is never modified.
+type memFS struct {
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string][]byte{}, dirs: map[string]bool{}}
+}
+
+func (m *memFS) Stat(path string) (os.FileInfo, error) {
+	path = filepath.Clean(path)
+	if data, ok := m.files[path]; ok {
+		return memFileInfo{name: filepath.Base(path), size: int64(len(data))}, nil
+	}
+	if m.dirs[path] {
+		return memFileInfo{name: filepath.Base(path), dir: true}, nil
+	}
+	return os.Stat(path)
+}
+
+func (m *memFS) ReadFile(path string) ([]byte, error) {
+	if data, ok := m.files[filepath.Clean(path)]; ok {
+		return data, nil
+	}
+	return os.ReadFile(path)
+}
+
+func (m *memFS) MkdirAll(path string, perm os.FileMode) error {
+	for p := filepath.Clean(path); p != "." && p != string(filepath.Separator); p = filepath.Dir(p) {
+		m.dirs[p] = true
+	}
+	return nil
+}
+
+func (m *memFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	m.files[filepath.Clean(path)] = append([]byte(nil), data...)
+	return nil
+}
+
+// Paths returns the written file paths in sorted order.
+func (m *memFS) Paths() []string {
+	paths := make([]string, 0, len(m.files))
+	for path := range m.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Report prints every recorded write as a create or overwrite of the on-disk file with its
+// size. With showDiff it also prints a unified diff against the current disk contents.
+func (m *memFS) Report(showDiff bool) {
+	paths := m.Paths()
+	if len(paths) == 0 {
+		fmt.Println("Nothing would be written.")
+		return
+	}
+
+	fmt.Println("Dry run: no files were written. The following changes would be made:")
+	for _, path := range paths {
+		data := m.files[path]
+		existing, err := os.ReadFile(path)
+		action := "create"
+		if err == nil {
+			action = "overwrite"
+			if string(existing) == string(data) {
+				action = "unchanged"
+			}
+		}
+		fmt.Printf("  %-9s %s (%d bytes)\n", action, filepath.ToSlash(path), len(data))
+	}
+
+	if !showDiff {
+		return
+	}
+	for _, path := range paths {
+		existing, err := os.ReadFile(path)
+		oldName := "a/" + filepath.ToSlash(path)
+		if err != nil {
+			existing, oldName = nil, "/dev/null"
+		}
+		if diff := unifiedDiff(oldName, "b/"+filepath.ToSlash(path), existing, m.files[path]); diff != "" {
+			fmt.Print("\n" + diff)
+		}
+	}
+}
+
+// memFileInfo describes a file or directory that only exists in a memFS.
+type memFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi memFileInfo) IsDir() bool        { return fi.dir }
+func (fi memFileInfo) Sys() interface{}   { return nil }
+func (fi memFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// reportf prints generator progress. In-memory runs stay quiet, because their writes are
+// summarised by memFS.Report instead.
+func reportf(fsys projectFS, format string, args ...interface{}) {
+	if _, ok := fsys.(*memFS); ok {
+		return
+	}
+	fmt.Printf(format, args...)
+}
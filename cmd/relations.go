@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relation kinds accepted in relation specs, e.g. "items:has-many:line-items".
+const (
+	RelationBelongsTo  = "belongs-to"
+	RelationHasMany    = "has-many"
+	RelationManyToMany = "many-to-many" // "many2many" is an alias
+)
+
+var relationKinds = []string{RelationBelongsTo, RelationHasMany, RelationManyToMany}
+
+// EntityRelation is an association between two entities, parsed from a spec such as
+// "customer:belongs-to:user:optional" or "tags:many-to-many:tags".
+type EntityRelation struct {
+	Entity     string // Go type name of the owning entity, e.g. "Orders"
+	Name       string // Relation name as given in the spec
+	Kind       string // One of the Relation* kinds
+	Target     string // Target entity as given in the spec
+	Optional   bool   // A belongs-to relation whose foreign key may be NULL
+	ForeignKey string // Go name of the foreign key: on the owner for belongs-to, on the target for has-many
+}
+
+// parseRelationSpecs parses the relation specs of the entity whose Go type is entity.
+func parseRelationSpecs(entity string, specs []string) ([]EntityRelation, error) {
+	relations := make([]EntityRelation, 0, len(specs))
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		r, err := parseRelationSpec(entity, spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[r.GoName()] {
+			return nil, fmt.Errorf("relation '%s' is declared twice", r.Name)
+		}
+		seen[r.GoName()] = true
+		relations = append(relations, r)
+	}
+	return relations, nil
+}
+
+func parseRelationSpec(entity, spec string) (EntityRelation, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	if len(parts) < 3 {
+		return EntityRelation{}, fmt.Errorf("invalid relation '%s': expected name:kind:entity[:optional], e.g. items:has-many:line-items", spec)
+	}
+	r := EntityRelation{Entity: entity, Name: parts[0], Kind: parts[1], Target: parts[2]}
+	if !fieldNamePattern.MatchString(r.Name) {
+		return r, fmt.Errorf("invalid relation name '%s': must start with a letter and contain only letters, digits or '_'", r.Name)
+	}
+	if r.GoName() == "All" || r.GoName() == "ByID" {
+		return r, fmt.Errorf("relation name '%s' is reserved: it would clash with the generated Get%s handler", r.Name, r.GoName())
+	}
+	if r.Kind == "many2many" {
+		r.Kind = RelationManyToMany
+	}
+	if !containsString(relationKinds, r.Kind) {
+		return r, fmt.Errorf("unknown kind '%s' for relation '%s'; expected one of %s", r.Kind, r.Name, strings.Join(relationKinds, ", "))
+	}
+	if !serviceNamePattern.MatchString(r.Target) {
+		return r, fmt.Errorf("invalid target entity '%s' for relation '%s'", r.Target, r.Name)
+	}
+	for _, m := range parts[3:] {
+		if m != ModifierOptional && m != "nullable" {
+			return r, fmt.Errorf("unknown modifier '%s' for relation '%s'; only belongs-to relations accept 'optional'", m, r.Name)
+		}
+		if r.Kind != RelationBelongsTo {
+			return r, fmt.Errorf("relation '%s': only belongs-to relations can be optional", r.Name)
+		}
+		r.Optional = true
+	}
+	if r.Kind == RelationBelongsTo {
+		r.ForeignKey = r.GoName() + "ID"
+	}
+	return r, nil
+}
+
+// String returns the canonical spec of the relation, as recorded in gores.yaml.
+func (r EntityRelation) String() string {
+	spec := strings.Join([]string{r.Name, r.Kind, r.Target}, ":")
+	if r.Optional {
+		spec += ":" + ModifierOptional
+	}
+	return spec
+}
+
+// GoName is the exported Go name of the association field, e.g. "Items".
+func (r EntityRelation) GoName() string {
+	return toPascalCase(r.Name)
+}
+
+// JSONName is the JSON key of the association and its name in ?include=, e.g. "line_items".
+func (r EntityRelation) JSONName() string {
+	return snakeCase(r.Name)
+}
+
+// RoutePath is the path segment of the nested route listing a has-many or many-to-many
+// relation, e.g. "line-items" in /orders/:id/line-items.
+func (r EntityRelation) RoutePath() string {
+	return strings.ReplaceAll(snakeCase(r.Name), "_", "-")
+}
+
+// TargetType is the Go type name of the related entity.
+func (r EntityRelation) TargetType() string {
+	return toPascalCase(r.Target)
+}
+
+// IsCollection reports whether the relation holds many related entities.
+func (r EntityRelation) IsCollection() bool {
+	return r.Kind != RelationBelongsTo
+}
+
+// GoType is the Go type of the association field.
+func (r EntityRelation) GoType() string {
+	if r.IsCollection() {
+		return "[]" + r.TargetType()
+	}
+	return "*" + r.TargetType()
+}
+
+// JoinTable is the many-to-many join table, e.g. "orders_tags".
+func (r EntityRelation) JoinTable() string {
+	return snakeCase(r.Entity) + "_" + snakeCase(r.Name)
+}
+
+// JoinKeys are the Go names of the join table's columns referencing the owner and the
+// target of a many-to-many relation, e.g. "OrdersID" and "TagsID". A relation between
+// entities of the same type names the target's column after the relation instead.
+func (r EntityRelation) JoinKeys() (owner, target string) {
+	if r.TargetType() == r.Entity {
+		return r.Entity + "ID", r.GoName() + "ID"
+	}
+	return r.Entity + "ID", r.TargetType() + "ID"
+}
+
+// GormTag is the content of the association field's gorm struct tag.
+func (r EntityRelation) GormTag() string {
+	if r.Kind == RelationManyToMany {
+		owner, target := r.JoinKeys()
+		return "many2many:" + r.JoinTable() + ";joinForeignKey:" + owner + ";joinReferences:" + target
+	}
+	return "foreignKey:" + r.ForeignKey
+}
+
+// foreignKeyField is the column a belongs-to relation stores on its owner.
+func (r EntityRelation) foreignKeyField() EntityField {
+	return EntityField{
+		Entity:   r.Entity,
+		Name:     r.ForeignKey,
+		Kind:     FieldUUID,
+		Optional: r.Optional,
+		Required: !r.Optional,
+		Indexed:  true,
+	}
+}
+
+// linkEntities resolves the relations between the entities of one service: belongs-to
+// relations get their foreign key column, and has-many relations reuse the target's
+// belongs-to relation back to the owner or add a nullable foreign key to the target.
+func linkEntities(entities []EntitySpec) error {
+	index := map[string]int{}
+	for i, e := range entities {
+		index[e.Type()] = i
+	}
+	for i := range entities {
+		for _, r := range entities[i].Relations {
+			if r.Kind == RelationBelongsTo {
+				entities[i].ForeignKeys = append(entities[i].ForeignKeys, r.foreignKeyField())
+			}
+		}
+	}
+	for i := range entities {
+		owner := &entities[i]
+		for j := range owner.Relations {
+			r := &owner.Relations[j]
+			if r.Kind != RelationHasMany {
+				continue
+			}
+			t, ok := index[r.TargetType()]
+			if !ok {
+				return fmt.Errorf("entity '%s': has-many relation '%s' targets '%s', which must be declared in the same service", owner.Name, r.Name, r.Target)
+			}
+			target := &entities[t]
+			for _, back := range target.Relations {
+				if back.Kind == RelationBelongsTo && back.TargetType() == owner.Type() {
+					r.ForeignKey = back.ForeignKey
+					break
+				}
+			}
+			if r.ForeignKey == "" {
+				r.ForeignKey = owner.Type() + "ID"
+				if !target.hasForeignKey(r.ForeignKey) {
+					target.ForeignKeys = append(target.ForeignKeys, EntityField{
+						Entity: target.Type(), Name: r.ForeignKey, Kind: FieldUUID, Optional: true, Indexed: true,
+					})
+				}
+			}
+		}
+	}
+
+	// Foreign keys and associations share the struct and table with the declared fields.
+	for _, e := range entities {
+		taken := map[string]string{"ID": "id", "id": "id", "CreatedAt": "created_at", "created_at": "created_at",
+			"UpdatedAt": "updated_at", "updated_at": "updated_at"}
+		for _, f := range e.AllFields() {
+			for _, name := range []string{f.GoName(), f.Column()} {
+				if prev, ok := taken[name]; ok {
+					return fmt.Errorf("entity '%s': field '%s' clashes with '%s'", e.Name, f.Name, prev)
+				}
+				taken[name] = f.Name
+			}
+		}
+		for _, r := range e.Relations {
+			if prev, ok := taken[r.GoName()]; ok {
+				return fmt.Errorf("entity '%s': relation '%s' clashes with '%s'", e.Name, r.Name, prev)
+			}
+			taken[r.GoName()] = r.Name
+		}
+	}
+	return nil
+}
+
+func (e EntitySpec) hasForeignKey(goName string) bool {
+	for _, f := range e.ForeignKeys {
+		if f.GoName() == goName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRelationSpec(t *testing.T) {
+	cases := []struct {
+		spec string
+		want EntityRelation
+	}{
+		{"customer:belongs-to:user", EntityRelation{Entity: "Orders", Name: "customer", Kind: RelationBelongsTo, Target: "user", ForeignKey: "CustomerID"}},
+		{"customer:belongs-to:user:optional", EntityRelation{Entity: "Orders", Name: "customer", Kind: RelationBelongsTo, Target: "user", Optional: true, ForeignKey: "CustomerID"}},
+		{"items:has-many:line-items", EntityRelation{Entity: "Orders", Name: "items", Kind: RelationHasMany, Target: "line-items"}},
+		{"tags:many2many:tags", EntityRelation{Entity: "Orders", Name: "tags", Kind: RelationManyToMany, Target: "tags"}},
+	}
+	for _, tc := range cases {
+		got, err := parseRelationSpec("Orders", tc.spec)
+		if err != nil {
+			t.Errorf("parseRelationSpec(%q): %v", tc.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseRelationSpec(%q) = %+v, want %+v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseRelationSpecErrors(t *testing.T) {
+	cases := map[string]string{
+		"customer":                           "expected name:kind:entity",
+		"customer:owns:user":                 "unknown kind 'owns'",
+		"9lives:has-many:cats":               "invalid relation name",
+		"all:has-many:line-items":            "reserved",
+		"items:has-many:Line Items":          "invalid target entity",
+		"items:has-many:line-items:maybe":    "unknown modifier 'maybe'",
+		"items:has-many:line-items:optional": "only belongs-to relations can be optional",
+	}
+	for spec, want := range cases {
+		_, err := parseRelationSpec("Orders", spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseRelationSpec(%q) error = %v, want it to mention %q", spec, err, want)
+		}
+	}
+}
+
+func relatedEntities(t *testing.T, relations map[string][]string) []EntitySpec {
+	t.Helper()
+	var entities []EntitySpec
+	for _, name := range []string{"orders", "line-items"} {
+		e := newEntitySpec(name, nil)
+		var err error
+		if e.Relations, err = parseRelationSpecs(e.Type(), relations[name]); err != nil {
+			t.Fatal(err)
+		}
+		entities = append(entities, e)
+	}
+	return entities
+}
+
+func TestLinkEntities(t *testing.T) {
+	// A has-many relation reuses the target's belongs-to relation back to the owner.
+	entities := relatedEntities(t, map[string][]string{
+		"orders":     {"items:has-many:line-items"},
+		"line-items": {"order:belongs-to:orders"},
+	})
+	if err := linkEntities(entities); err != nil {
+		t.Fatal(err)
+	}
+	if got := entities[0].Relations[0].GormTag(); got != "foreignKey:OrderID" {
+		t.Errorf("items gorm tag = %q", got)
+	}
+	if got := fieldSpecs(entities[1].ForeignKeys); !reflect.DeepEqual(got, []string{"OrderID:uuid:required:indexed"}) {
+		t.Errorf("line-items foreign keys = %v", got)
+	}
+
+	// Without one, the target gets a nullable foreign key named after the owner.
+	entities = relatedEntities(t, map[string][]string{"orders": {"items:has-many:line-items", "tags:many-to-many:tags"}})
+	if err := linkEntities(entities); err != nil {
+		t.Fatal(err)
+	}
+	if got := fieldSpecs(entities[1].ForeignKeys); !reflect.DeepEqual(got, []string{"OrdersID:uuid:optional:indexed"}) {
+		t.Errorf("line-items foreign keys = %v", got)
+	}
+	if got := entities[0].Relations[1].GormTag(); got != "many2many:orders_tags;joinForeignKey:OrdersID;joinReferences:TagsID" {
+		t.Errorf("tags gorm tag = %q", got)
+	}
+	if got := entities[0].Relations[0].GoType() + " " + entities[0].Relations[1].GoType(); got != "[]LineItems []Tags" {
+		t.Errorf("association types = %q", got)
+	}
+}
+
+func TestLinkEntitiesErrors(t *testing.T) {
+	cases := []struct {
+		relations map[string][]string
+		want      string
+	}{
+		{map[string][]string{"orders": {"refunds:has-many:refunds"}}, "must be declared in the same service"},
+		{map[string][]string{"orders": {"createdAt:belongs-to:user"}}, "clashes with 'created_at'"},
+		{map[string][]string{"orders": {"customer:belongs-to:user", "customerID:has-many:line-items"}}, "clashes with"},
+	}
+	for _, tc := range cases {
+		err := linkEntities(relatedEntities(t, tc.relations))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("linkEntities(%v) error = %v, want it to mention %q", tc.relations, err, tc.want)
+		}
+	}
+}
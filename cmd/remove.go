@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	removeKeepFiles bool
+	removeYes       bool
+)
+
+// removeCmd is the Cobra command for deleting a generated microservice.
+var removeCmd = &cobra.Command{
+	Use:   "remove [service-name]",
+	Short: "Remove a generated microservice",
+	Long: "Unregisters a service from gores.yaml, frees its port, drops it from go.work and Docker Compose files, " +
+		"and deletes its 'services/<name>' directory, shared entity files and typed client. Use --keep-files to only unregister it.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --- Prerequisite Check ---
+		manifest, err := loadProjectManifest()
+		if err != nil {
+			return err
+		}
+		// --- End Prerequisite Check ---
+
+		// The name ends up in the paths deleted below, so only registered services with a
+		// valid name are accepted; '../pkg' must never reach os.RemoveAll.
+		serviceName := args[0]
+		if !serviceNamePattern.MatchString(serviceName) {
+			return fmt.Errorf("invalid service name '%s': must start with a letter and contain only letters, digits, '-' or '_'", serviceName)
+		}
+		entry := manifest.Service(serviceName)
+		if entry == nil {
+			return fmt.Errorf("service '%s' is not registered in %s", serviceName, ManifestFile)
+		}
+		servicePath := filepath.Join(servicesDir, serviceName)
+		clientPath := clientDir(serviceName)
+		if err := checkWithin(servicesDir, servicePath); err != nil {
+			return err
+		}
+		if err := checkWithin(filepath.Dir(clientPath), clientPath); err != nil {
+			return err
+		}
+		dirExists := ServiceExists(serviceName)
+
+		template := entry.Template
+		entityFiles := serviceEntityFiles(entry)
+		for _, entityPath := range entityFiles {
+			if err := checkWithin(filepath.Join("pkg", "entities"), entityPath); err != nil {
+				return err
+			}
+		}
+		_, err = os.Stat(clientPath)
+		clientExists := err == nil
+
+		// Describe what is about to happen before asking for confirmation.
+		fmt.Printf("Removing service '%s':\n", serviceName)
+		fmt.Printf("  - unregister from %s and free port %d\n", ManifestFile, entry.Port)
+		fmt.Printf("  - drop references from %s and Docker Compose files (if any)\n", goWorkFile)
+		if !removeKeepFiles {
+			if dirExists {
+				fmt.Printf("  - delete directory %s\n", servicePath)
+			}
+			for _, entityPath := range entityFiles {
+				if _, err := os.Stat(entityPath); err == nil {
+					fmt.Printf("  - delete entity file %s\n", entityPath)
+				}
+			}
+			if clientExists {
+				fmt.Printf("  - delete client package %s\n", clientPath)
+			}
+		}
+		if template == TemplateAuth {
+			fmt.Println("Warning: other services may depend on the auth service and its User entity.")
+		}
+
+		if !removeYes && !confirm(cmd.InOrStdin(), "Proceed?") {
+			fmt.Println("Aborted.")
+			return nil
+		}
+
+		if err := ReleaseService(ManifestFile, serviceName); err != nil {
+			return fmt.Errorf("failed to unregister service '%s': %w", serviceName, err)
+		}
+		fmt.Printf("Unregistered '%s' and freed port %d.\n", serviceName, entry.Port)
+
+		if changed, err := dropWorkspaceUse(serviceModuleDir(serviceName)); err != nil {
+			return err
+		} else if changed {
+			fmt.Printf("Removed '%s' from %s.\n", serviceModuleDir(serviceName), goWorkFile)
+		}
+
+		composeChanged, err := dropComposeService(serviceName)
+		if err != nil {
+			return err
+		}
+		for _, file := range composeChanged {
+			fmt.Printf("Removed '%s' from %s.\n", serviceName, file)
+		}
+
+		if removeKeepFiles {
+			fmt.Printf("Service '%s' unregistered; files were kept.\n", serviceName)
+			return nil
+		}
+
+		if dirExists {
+			if err := os.RemoveAll(servicePath); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", servicePath, err)
+			}
+			fmt.Printf("Deleted: %s\n", servicePath)
+		}
+		for _, entityPath := range entityFiles {
+			if err := os.Remove(entityPath); err == nil {
+				fmt.Printf("Deleted: %s\n", entityPath)
+			} else if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete %s: %w", entityPath, err)
+			}
+		}
+		if clientExists {
+			if err := os.RemoveAll(clientPath); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", clientPath, err)
+			}
+			fmt.Printf("Deleted: %s\n", clientPath)
+		}
+
+		if err := dropServiceBaselines(serviceName, template, append(entityFiles, clientFiles(serviceName)...)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to drop upgrade baselines of '%s': %v\n", serviceName, err)
+		}
+
+		fmt.Printf("Service '%s' removed successfully.\n", serviceName)
+		return nil
+	},
+}
+
+// checkWithin fails unless path lies strictly inside the directory root once cleaned.
+func checkWithin(root, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return fmt.Errorf("refusing to delete %s: it is outside %s", path, root)
+	}
+	return nil
+}
+
+// confirm prints a yes/no prompt and reports whether the answer read from in was yes.
+func confirm(in io.Reader, prompt string) bool {
+	fmt.Printf("%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	removeCmd.Flags().BoolVar(&removeKeepFiles, "keep-files", false, "Only unregister the service; keep its directory and entity file on disk")
+	removeCmd.Flags().BoolVarP(&removeYes, "yes", "y", false, "Skip the confirmation prompt")
+	rootCmd.AddCommand(removeCmd)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newOrdersProject generates a project with the shared module and an "orders" service,
+// registered in the manifest and go.work, and records their baselines.
+func newOrdersProject(t *testing.T) {
+	t.Helper()
+	newServicesProject(t, "orders")
+	if err := os.WriteFile("docker-compose.yml", []byte(testCompose), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newServicesProject generates a project with the shared module and the named services on
+// ports from 8081 up, registered in the manifest and go.work, and records their baselines.
+func newServicesProject(t *testing.T, names ...string) {
+	t.Helper()
+	chdir(t, t.TempDir())
+	opts := generatorOptions{Module: defaultModulePath}
+	generators := []func() (generatedFiles, error){
+		func() (generatedFiles, error) { return createSharedPkg(osFS{}, opts) },
+	}
+	m := NewManifest(defaultModulePath)
+	dirs := []string{pkgModuleDir}
+	for i, name := range names {
+		name, port := name, 8081+i
+		generators = append(generators, func() (generatedFiles, error) {
+			return createMicroservice(osFS{}, opts, name, strconv.Itoa(port), "templates/", nil)
+		})
+		if err := m.AddService(newServiceEntry(name, port, TemplateGeneric)); err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, serviceModuleDir(name))
+	}
+	files, err := generateAll(generators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recordBaselines(files); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveManifest(ManifestFile, m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncWorkspace(osFS{}, dirs, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testCompose runs the orders service next to a gateway depending on it.
+const testCompose = `services:
+  orders:
+    build: ./services/orders
+  gateway:
+    image: nginx
+    depends_on:
+      - orders
+`
+
+// runRemove runs 'gores remove name --yes', with --keep-files when keepFiles is set.
+func runRemove(t *testing.T, name string, keepFiles bool) error {
+	t.Helper()
+	removeYes, removeKeepFiles = true, keepFiles
+	t.Cleanup(func() { removeYes, removeKeepFiles = false, false })
+	return removeCmd.RunE(removeCmd, []string{name})
+}
+
+func TestRemoveRejectsNamesOutsideServices(t *testing.T) {
+	newOrdersProject(t)
+	goWork, err := os.ReadFile(goWorkFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"../pkg", "..", ".", "orders/../../pkg", "/tmp", "billing"} {
+		if err := runRemove(t, name, false); err == nil {
+			t.Errorf("remove %q succeeded, want an error", name)
+		}
+	}
+
+	for _, path := range []string{filepath.Join("pkg", "go.mod"), clientsPkgFile, filepath.Join(servicesDir, "orders", "go.mod")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was deleted: %v", path, err)
+		}
+	}
+	if content, _ := os.ReadFile(goWorkFile); string(content) != string(goWork) {
+		t.Errorf("go.work changed:\n%s\nwant:\n%s", content, goWork)
+	}
+}
+
+func TestCheckWithin(t *testing.T) {
+	for _, tt := range []struct {
+		path string
+		ok   bool
+	}{
+		{filepath.Join(servicesDir, "orders"), true},
+		{filepath.Join(servicesDir, "orders", "internal"), true},
+		{servicesDir, false},
+		{filepath.Join(servicesDir, ".."), false},
+		{filepath.Join(servicesDir, "..", "pkg"), false},
+		{"pkg", false},
+	} {
+		if err := checkWithin(servicesDir, tt.path); (err == nil) != tt.ok {
+			t.Errorf("checkWithin(%q) = %v, want ok=%v", tt.path, err, tt.ok)
+		}
+	}
+}
+
+func TestRemoveCleansUpService(t *testing.T) {
+	newOrdersProject(t)
+
+	if err := runRemove(t, "orders", false); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	m, err := LoadManifest(ManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Service("orders") != nil || m.IsPortUsed(8081) {
+		t.Errorf("orders is still registered: %+v", m.Services)
+	}
+	if content, _ := os.ReadFile(goWorkFile); strings.Contains(string(content), "services/orders") {
+		t.Errorf("go.work still uses the service:\n%s", content)
+	}
+	compose, err := os.ReadFile("docker-compose.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(compose), "orders") || !strings.Contains(string(compose), "gateway:") {
+		t.Errorf("docker-compose.yml still refers to orders or lost the gateway:\n%s", compose)
+	}
+	for _, path := range []string{
+		filepath.Join(servicesDir, "orders"),
+		entityFilePath("orders", TemplateGeneric),
+		clientDir("orders"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not deleted (err: %v)", path, err)
+		}
+	}
+	// The shared module is kept, with its clients runtime.
+	if _, err := os.Stat(clientsPkgFile); err != nil {
+		t.Errorf("%s was deleted: %v", clientsPkgFile, err)
+	}
+
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key := range idx.Files {
+		if strings.Contains(key, "orders") {
+			t.Errorf("the baseline of %s was kept", key)
+		}
+	}
+	if _, ok := idx.Files[baselineKey(clientsPkgFile)]; !ok {
+		t.Errorf("the baseline of %s was dropped", clientsPkgFile)
+	}
+}
+
+func TestRemoveKeepFiles(t *testing.T) {
+	newOrdersProject(t)
+
+	if err := runRemove(t, "orders", true); err != nil {
+		t.Fatalf("remove --keep-files: %v", err)
+	}
+
+	m, err := LoadManifest(ManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Service("orders") != nil {
+		t.Errorf("orders is still registered")
+	}
+	if content, _ := os.ReadFile(goWorkFile); strings.Contains(string(content), "services/orders") {
+		t.Errorf("go.work still uses the service:\n%s", content)
+	}
+	for _, path := range []string{
+		filepath.Join(servicesDir, "orders", "go.mod"),
+		entityFilePath("orders", TemplateGeneric),
+		clientFiles("orders")[0],
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was deleted: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,1262 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
+	"gopkg.in/yaml.v3"
+)
+
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// renameCmd is the Cobra command for renaming a generated microservice.
+var renameCmd = &cobra.Command{
+	Use:   "rename [old-name] [new-name]",
+	Short: "Rename a generated microservice across the monorepo",
+	Long: "Renames a service's directory, go.mod module path, package name, generated Go identifiers " +
+		"(e.g. OrdersController -> InvoicesController), shared entity file and manifest entry. Go sources are " +
+		"rewritten through go/ast, and references to the entity from other modules are updated as well.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --- Prerequisite Check ---
+		manifest, err := loadProjectManifest()
+		if err != nil {
+			return err
+		}
+		// --- End Prerequisite Check ---
+
+		oldName, newName := args[0], args[1]
+		if oldName == newName {
+			return fmt.Errorf("old and new service names are identical")
+		}
+		if !serviceNamePattern.MatchString(newName) {
+			return fmt.Errorf("invalid service name '%s': must start with a letter and contain only letters, digits, '-' or '_'", newName)
+		}
+
+		entry := manifest.Service(oldName)
+		if entry == nil {
+			return fmt.Errorf("service '%s' is not registered in %s", oldName, ManifestFile)
+		}
+		oldPath := filepath.Join(servicesDir, oldName)
+		newPath := filepath.Join(servicesDir, newName)
+		if !ServiceExists(oldName) {
+			return fmt.Errorf("service directory %s does not exist", oldPath)
+		}
+		if manifest.Service(newName) != nil || ServiceExists(newName) {
+			return fmt.Errorf("a service with the name '%s' already exists", newName)
+		}
+
+		plan, err := planRename(manifest, oldName, newName)
+		if err != nil {
+			return err
+		}
+
+		// Every rewrite has been computed and parsed successfully; apply them.
+		for _, file := range plan.order {
+			if err := writeFileAtomic(file, plan.writes[file], 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Rewritten: %s\n", file)
+		}
+		if plan.oldEntity != "" {
+			if err := writeFileAtomic(plan.newEntity, plan.entityContent, 0644); err != nil {
+				return err
+			}
+			if err := os.Remove(plan.oldEntity); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", plan.oldEntity, err)
+			}
+			fmt.Printf("Renamed: %s -> %s\n", plan.oldEntity, plan.newEntity)
+		}
+		for _, m := range plan.moves {
+			if err := os.MkdirAll(filepath.Dir(m.to), os.ModePerm); err != nil {
+				return fmt.Errorf("failed to create folder %s: %w", filepath.Dir(m.to), err)
+			}
+			if err := writeFileAtomic(m.to, m.content, 0644); err != nil {
+				return err
+			}
+			if err := os.Remove(m.from); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", m.from, err)
+			}
+			os.Remove(filepath.Dir(m.from)) // Only succeeds once the directory is empty.
+			fmt.Printf("Renamed: %s -> %s\n", m.from, m.to)
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return fmt.Errorf("failed to rename %s to %s: %w", oldPath, newPath, err)
+		}
+		fmt.Printf("Renamed: %s -> %s\n", oldPath, newPath)
+
+		var related []string
+		_, err = UpdateManifest(ManifestFile, func(m *Manifest) error {
+			related, err = renameManifestService(m, oldName, newName)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("files were renamed but updating %s failed: %w", ManifestFile, err)
+		}
+		if len(plan.moves) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: code calling '%s' through its client must now import %s/%s.\n",
+				newName, manifest.Module+"/pkg/clients", newName)
+		}
+		for _, name := range related {
+			fmt.Fprintf(os.Stderr, "Warning: service '%s' relates to entities.%s; run 'gores upgrade %s' to regenerate it against entities.%s.\n",
+				name, toPascalCase(oldName), name, toPascalCase(newName))
+		}
+
+		if err := renameServiceBaselines(plan, oldName, newName, entry.Template); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to move upgrade baselines of '%s': %v\n", oldName, err)
+		}
+
+		if changed, err := renameWorkspaceUse(serviceModuleDir(oldName), serviceModuleDir(newName)); err != nil {
+			return err
+		} else if changed {
+			fmt.Printf("Updated %s.\n", goWorkFile)
+		}
+		composeChanged, err := renameComposeService(oldName, newName)
+		if err != nil {
+			return err
+		}
+		for _, file := range composeChanged {
+			fmt.Printf("Updated %s.\n", file)
+		}
+
+		fmt.Printf("Service '%s' renamed to '%s' (port %d unchanged).\n", oldName, newName, entry.Port)
+		return nil
+	},
+}
+
+// renameManifestService renames the entry of service oldName, and the entity named after
+// it, to newName. It returns the services whose relations targeted that entity.
+func renameManifestService(m *Manifest, oldName, newName string) ([]string, error) {
+	if m.Service(newName) != nil {
+		return nil, fmt.Errorf("a service with the name '%s' is already registered in %s", newName, ManifestFile)
+	}
+	s := m.Service(oldName)
+	if s == nil {
+		return nil, fmt.Errorf("service '%s' is no longer registered in %s", oldName, ManifestFile)
+	}
+	s.Name = newName
+	for i := range s.Entities {
+		if s.Entities[i].Name == oldName {
+			s.Entities[i].Name = newName
+		}
+	}
+	if numbers, ok := s.ProtoFields[oldName]; ok {
+		delete(s.ProtoFields, oldName)
+		s.ProtoFields[newName] = numbers
+	}
+	return renameRelationTargets(m, oldName, newName), nil
+}
+
+// renameRelationTargets points every relation spec targeting the entity oldName at
+// newName and returns the services whose relations changed.
+func renameRelationTargets(m *Manifest, oldName, newName string) []string {
+	rename := func(specs []string) bool {
+		changed := false
+		for i, spec := range specs {
+			parts := strings.Split(spec, ":")
+			if len(parts) >= 3 && toPascalCase(parts[2]) == toPascalCase(oldName) {
+				parts[2] = newName
+				specs[i] = strings.Join(parts, ":")
+				changed = true
+			}
+		}
+		return changed
+	}
+	var services []string
+	for i := range m.Services {
+		s := &m.Services[i]
+		changed := rename(s.Relations)
+		for _, e := range s.Entities {
+			specs := make([]string, len(e.Relations))
+			for k, r := range e.Relations {
+				specs[k] = string(r)
+			}
+			if rename(specs) {
+				for k, spec := range specs {
+					e.Relations[k] = SchemaRelation(spec)
+				}
+				changed = true
+			}
+		}
+		if changed {
+			services = append(services, s.Name)
+		}
+	}
+	return services
+}
+
+// renamePlan holds every file rewrite for a rename, computed before anything is written.
+type renamePlan struct {
+	writes map[string][]byte
+	order  []string
+
+	oldEntity, newEntity string
+	entityContent        []byte
+	moves                []fileMove // Other files outside the service directory, e.g. its typed client
+
+	// baselines holds the new upgrade baselines of the documents rendered again for the new
+	// name, keyed by the baseline key of their old path.
+	baselines map[string][]byte
+
+	oldName, newName string
+	renamer          *goRenamer
+}
+
+// fileMove is a file that a rename moves to another path with new content.
+type fileMove struct {
+	from, to string
+	content  []byte
+}
+
+func (p *renamePlan) add(file string, content []byte) {
+	if _, ok := p.writes[file]; !ok {
+		p.order = append(p.order, file)
+	}
+	p.writes[file] = content
+}
+
+// planRename computes the new content of every file affected by renaming the service
+// oldName, registered in manifest, to newName.
+func planRename(manifest *Manifest, oldName, newName string) (*renamePlan, error) {
+	entry := manifest.Service(oldName)
+	if entry == nil {
+		return nil, fmt.Errorf("service '%s' is not registered in %s", oldName, ManifestFile)
+	}
+	template := entry.Template
+	plan := &renamePlan{writes: map[string][]byte{}, baselines: map[string][]byte{}}
+	servicePath := filepath.Join(servicesDir, oldName)
+
+	// go.mod: module path.
+	goModPath := filepath.Join(servicePath, "go.mod")
+	oldModule, newModule := "", ""
+	if content, err := os.ReadFile(goModPath); err == nil {
+		modFile, err := modfile.Parse(goModPath, content, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", goModPath, err)
+		}
+		if modFile.Module != nil {
+			oldModule = modFile.Module.Mod.Path
+			newModule = renameModulePath(oldModule, oldName, newName)
+			if newModule != oldModule {
+				if err := modFile.AddModuleStmt(newModule); err != nil {
+					return nil, fmt.Errorf("failed to update module path in %s: %w", goModPath, err)
+				}
+				formatted, err := modFile.Format()
+				if err != nil {
+					return nil, fmt.Errorf("failed to format %s: %w", goModPath, err)
+				}
+				plan.add(goModPath, formatted)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", goModPath, err)
+	}
+
+	r := newGoRenamer(oldName, newName, oldModule, newModule)
+	plan.oldName, plan.newName, plan.renamer = oldName, newName, r
+
+	// The templates rendered for both names tell which literals and comments derive from the
+	// name; the service's own sources tell which identifiers it declares.
+	before, after, err := plan.render(manifest)
+	if err != nil {
+		return nil, err
+	}
+	plan.learnRenderings(before, after)
+	if err := plan.declareSources(entry); err != nil {
+		return nil, err
+	}
+
+	// Go sources of the service itself.
+	err = filepath.WalkDir(servicePath, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(file) != ".go" {
+			return nil
+		}
+		content, changed, err := r.rewriteFile(file, false)
+		if err != nil {
+			return err
+		}
+		if changed {
+			plan.add(file, content)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Dockerfile: build paths and binary names are plain text.
+	dockerfilePath := filepath.Join(servicePath, "Dockerfile")
+	if content, err := os.ReadFile(dockerfilePath); err == nil {
+		updated := replaceWord(string(content), oldName, newName)
+		if updated != string(content) {
+			plan.add(dockerfilePath, []byte(updated))
+		}
+	}
+
+	// Documents such as openapi.yaml derive operation IDs, tags and route paths from the
+	// name in ways a word replacement cannot follow, so they are rendered again.
+	if template == TemplateGeneric {
+		if err := plan.rerenderDocuments(after); err != nil {
+			return nil, err
+		}
+	}
+
+	// Shared entity file. The auth service's User entity is not named after the service.
+	var entityPath string
+	if template != TemplateAuth {
+		entityPath = entityFilePath(oldName, template)
+		if _, err := os.Stat(entityPath); err == nil {
+			content, _, err := r.rewriteFile(entityPath, false)
+			if err != nil {
+				return nil, err
+			}
+			plan.oldEntity = entityPath
+			plan.newEntity = entityFilePath(newName, template)
+			plan.entityContent = content
+		}
+	}
+
+	// Typed client in the shared module: its package and URL variable follow the name.
+	newClientFiles := clientFiles(newName)
+	for i, file := range clientFiles(oldName) {
+		content, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if content, err = plan.rewriteClient(file, content); err != nil {
+			return nil, err
+		}
+		plan.moves = append(plan.moves, fileMove{from: file, to: newClientFiles[i], content: content})
+	}
+
+	// References to the renamed entity from the rest of the monorepo.
+	for _, root := range []string{"pkg", servicesDir} {
+		err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == "vendor" || file == servicePath || file == clientDir(oldName) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(file) != ".go" || file == entityPath {
+				return nil
+			}
+			content, changed, err := r.rewriteFile(file, true)
+			if err != nil {
+				return err
+			}
+			if changed {
+				plan.add(file, content)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// renderedDocument reports whether a generated file of a service is a document that a rename
+// renders again: anything but Go sources, go.mod, go.sum and the Dockerfile.
+func renderedDocument(file string) bool {
+	switch filepath.Base(file) {
+	case "go.mod", "go.sum", "Dockerfile":
+		return false
+	}
+	return filepath.Ext(file) != ".go"
+}
+
+// render renders the service registered in manifest under its old name and under its new one.
+func (p *renamePlan) render(manifest *Manifest) (before, after []renderedFile, err error) {
+	before, err = renderUpgradeTarget(manifest, p.oldName)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := encodeManifest(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	renamed := &Manifest{}
+	if err := yaml.Unmarshal(content, renamed); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy %s: %w", ManifestFile, err)
+	}
+	renamed.applyDefaults()
+	if _, err := renameManifestService(renamed, p.oldName, p.newName); err != nil {
+		return nil, nil, err
+	}
+	after, err = renderUpgradeTarget(renamed, p.newName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// renamedPath is the path a file of the service moves to: into the new service directory,
+// or the new client package. Other files keep their path.
+func (p *renamePlan) renamedPath(file string) string {
+	oldDir := filepath.Join(servicesDir, p.oldName)
+	if rel, err := filepath.Rel(oldDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join(servicesDir, p.newName, rel)
+	}
+	if file == entityFilePath(p.oldName, TemplateGeneric) {
+		return entityFilePath(p.newName, TemplateGeneric)
+	}
+	newClientFiles := clientFiles(p.newName)
+	for i, f := range clientFiles(p.oldName) {
+		if file == f {
+			return newClientFiles[i]
+		}
+	}
+	return file
+}
+
+// learnRenderings teaches the renamer the literals and comments that the Go templates
+// render differently for the new name.
+func (p *renamePlan) learnRenderings(before, after []renderedFile) {
+	rendered := map[string][]byte{}
+	for _, f := range after {
+		rendered[filepath.Clean(f.path)] = f.content
+	}
+	for _, f := range before {
+		if filepath.Ext(f.path) != ".go" {
+			continue
+		}
+		if content, ok := rendered[p.renamedPath(filepath.Clean(f.path))]; ok {
+			p.renamer.learn(f.content, content)
+		}
+	}
+}
+
+// declareSources records the names declared by the Go sources of the service, its entities
+// and its typed client, which are the only identifiers the rename touches.
+func (p *renamePlan) declareSources(entry *ServiceEntry) error {
+	r := p.renamer
+	servicePath := filepath.Join(servicesDir, p.oldName)
+	err := filepath.WalkDir(servicePath, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(file) != ".go" {
+			return nil
+		}
+		pkg := r.oldModule
+		if rel, err := filepath.Rel(servicePath, filepath.Dir(file)); err == nil && rel != "." {
+			pkg = path.Join(pkg, filepath.ToSlash(rel))
+		}
+		return r.declareFile(pkg, file)
+	})
+	if err != nil {
+		return err
+	}
+	for _, file := range serviceEntityFiles(entry) {
+		if err := r.declareFile(entitiesPackage, file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	for _, file := range clientFiles(p.oldName) {
+		if err := r.declareFile(filepath.ToSlash(clientDir(p.oldName)), file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rerenderDocuments merges the edits made to the documents of the service since their
+// baseline into their rendering for the new name, which becomes their new baseline.
+// Documents without a baseline, or whose edits conflict with the new rendering, only get
+// the name replaced as text.
+func (p *renamePlan) rerenderDocuments(rendered []renderedFile) error {
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		return err
+	}
+
+	oldDir, newDir := filepath.Join(servicesDir, p.oldName), filepath.Join(servicesDir, p.newName)
+	labels := mergeLabels{ours: "yours", base: "baseline", theirs: "renamed"}
+	for _, f := range rendered {
+		rel, err := filepath.Rel(newDir, f.path)
+		if err != nil || strings.HasPrefix(rel, "..") || !renderedDocument(f.path) {
+			continue
+		}
+		file := filepath.Join(oldDir, rel)
+		ours, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		updated := []byte(replaceWord(string(ours), p.oldName, p.newName))
+		if base, ok := readBaseline(idx, file); ok {
+			merged, conflicts := merge3(base, ours, f.content, labels)
+			if conflicts == 0 {
+				updated = merged
+				p.baselines[baselineKey(file)] = f.content
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: your edits to %s conflict with its rendering for '%s'; only the name was replaced in it.\n", file, p.newName)
+			}
+		}
+		if string(updated) != string(ours) {
+			p.add(file, updated)
+		}
+	}
+	return nil
+}
+
+// rewriteContent applies the rename to the content of a file of the renamed service that is
+// not read from disk, such as its upgrade baseline. file selects the rewrite: go.mod gets
+// the new module path, Go sources go through the goRenamer and anything else is plain text.
+func (p *renamePlan) rewriteContent(file string, content []byte) ([]byte, error) {
+	switch {
+	case path.Dir(file) == filepath.ToSlash(clientDir(p.oldName)):
+		return p.rewriteClient(file, content)
+	case path.Base(file) == "go.mod":
+		modFile, err := modfile.Parse(file, content, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if modFile.Module == nil || p.renamer.newModule == "" || p.renamer.newModule == modFile.Module.Mod.Path {
+			return content, nil
+		}
+		if err := modFile.AddModuleStmt(p.renamer.newModule); err != nil {
+			return nil, fmt.Errorf("failed to update module path in %s: %w", file, err)
+		}
+		return modFile.Format()
+	case path.Ext(file) == ".go":
+		updated, _, err := p.renamer.rewriteSource(file, content, false)
+		return updated, err
+	default:
+		return []byte(replaceWord(string(content), p.oldName, p.newName)), nil
+	}
+}
+
+// rewriteClient applies the rename to a file of the service's typed client, which is
+// named after the service in its package name and URL variable as well.
+func (p *renamePlan) rewriteClient(file string, content []byte) ([]byte, error) {
+	r := *p.renamer
+	r.oldPkgs = []string{clientPackageName(p.oldName)}
+	r.newPkg = clientPackageName(p.newName)
+	updated, _, err := r.rewriteSource(file, content, false)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.ReplaceAll(updated, []byte(strconv.Quote(clientURLEnv(p.oldName))), []byte(strconv.Quote(clientURLEnv(p.newName)))), nil
+}
+
+// renameModulePath derives the module path of the renamed service from its old path.
+func renameModulePath(oldModule, oldName, newName string) string {
+	base := path.Base(oldModule)
+	var newBase string
+	switch base {
+	case oldName:
+		newBase = newName
+	case strings.ToLower(oldName):
+		newBase = strings.ToLower(newName)
+	default:
+		return oldModule // Not derived from the service name; leave it alone.
+	}
+	if dir := path.Dir(oldModule); dir != "." {
+		return dir + "/" + newBase
+	}
+	return newBase
+}
+
+// goRenamer rewrites the Go identifiers, strings, comments and import paths that gores
+// derives from a service name.
+type goRenamer struct {
+	oldIdents []string // Exported identifier forms of the old name, e.g. "Orders"
+	newIdent  string
+	oldVars   []string // lowerCamel forms starting identifiers, e.g. "orders" in ordersService
+	newVar    string
+	oldWord   string // Service name as used in paths, strings and comments
+	newWord   string
+	oldPkgs   []string // Package names generated from the old name
+	newPkg    string
+
+	oldModule, newModule string
+
+	// Only identifiers declared by the service's own code are renamed, so that the name
+	// inside imported ones, e.g. "Status" in http.StatusOK, is left alone.
+	decls   map[string]map[string]bool // Package-level names, by import path of their package
+	members map[string]bool            // Struct fields and methods
+	locals  map[string]bool            // Parameters, results and local variables
+
+	// Only the literals and comments that the templates render differently for the new
+	// name are rewritten; see learn.
+	texts      map[textKey]string
+	textValues map[string]string // By old text alone, for texts that always render the same
+}
+
+// entitiesPackage keys the names declared by the shared entities package in goRenamer.decls,
+// whatever its import path.
+const entitiesPackage = "entities"
+
+func newGoRenamer(oldName, newName, oldModule, newModule string) *goRenamer {
+	r := &goRenamer{
+		newIdent:   toPascalCase(newName),
+		newVar:     lowerFirst(toPascalCase(newName)),
+		oldWord:    oldName,
+		newWord:    newName,
+		newPkg:     goPackageName(newName),
+		oldModule:  oldModule,
+		newModule:  newModule,
+		decls:      map[string]map[string]bool{},
+		members:    map[string]bool{},
+		locals:     map[string]bool{},
+		texts:      map[textKey]string{},
+		textValues: map[string]string{},
+	}
+	for _, ident := range []string{toPascalCase(oldName), strings.Title(oldName)} {
+		if token.IsIdentifier(ident) && !containsString(r.oldIdents, ident) {
+			r.oldIdents = append(r.oldIdents, ident)
+			if v := lowerFirst(ident); !containsString(r.oldVars, v) {
+				r.oldVars = append(r.oldVars, v)
+			}
+		}
+	}
+	for _, pkg := range []string{goPackageName(oldName), strings.ToLower(oldName)} {
+		if !containsString(r.oldPkgs, pkg) {
+			r.oldPkgs = append(r.oldPkgs, pkg)
+		}
+	}
+	return r
+}
+
+// declareFile records the names declared by a Go file of the package with the given import path.
+func (r *goRenamer) declareFile(pkg, file string) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), file, src, 0)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s (fix syntax errors before renaming): %w", file, err)
+	}
+	r.declare(pkg, f)
+	return nil
+}
+
+// declare records the names declared by f, a file of the package with the given import path.
+func (r *goRenamer) declare(pkg string, f *ast.File) {
+	if r.decls[pkg] == nil {
+		r.decls[pkg] = map[string]bool{}
+	}
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				r.decls[pkg][d.Name.Name] = true
+			} else {
+				r.members[d.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, name := range specNames(d) {
+				r.decls[pkg][name] = true
+			}
+		}
+	}
+
+	fields := func(list *ast.FieldList, names map[string]bool) {
+		if list == nil {
+			return
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				names[name.Name] = true
+			}
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.StructType:
+			// An embedded field is named after its type.
+			for _, field := range n.Fields.List {
+				if len(field.Names) == 0 {
+					if name := embeddedName(field.Type); name != "" {
+						r.members[name] = true
+					}
+				}
+			}
+			fields(n.Fields, r.members)
+		case *ast.InterfaceType:
+			fields(n.Methods, r.members)
+		case *ast.FuncType:
+			fields(n.Params, r.locals)
+			fields(n.Results, r.locals)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						r.locals[id.Name] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, e := range []ast.Expr{n.Key, n.Value} {
+					if id, ok := e.(*ast.Ident); ok {
+						r.locals[id.Name] = true
+					}
+				}
+			}
+		case *ast.DeclStmt:
+			if d, ok := n.Decl.(*ast.GenDecl); ok {
+				for _, name := range specNames(d) {
+					r.locals[name] = true
+				}
+			}
+		}
+		return true
+	})
+}
+
+// specNames returns the names of the types, variables and constants a declaration declares.
+func specNames(d *ast.GenDecl) []string {
+	var names []string
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return names
+}
+
+// embeddedName returns the field name of an embedded field of the given type.
+func embeddedName(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	}
+	return ""
+}
+
+// textKey identifies a string literal or comment of a Go file across renderings.
+type textKey struct {
+	decl string // Top-level declaration holding the text, e.g. "*OrdersController.GetAll"
+	text string // Literal value with its quotes, or comment text
+	n    int    // Occurrences of the same text earlier in the declaration
+}
+
+// sourceText is a string literal or comment of a parsed Go file.
+type sourceText struct {
+	key  textKey
+	text *string // Where the text is stored in the syntax tree
+}
+
+// learn records how the literals and comments of a Go file rendered for the old name,
+// before, read when rendered for the new name, after. Texts that render the same, such as
+// the "status" key of a health check response, are left alone by the rename even when they
+// contain the name.
+func (r *goRenamer) learn(before, after []byte) {
+	fb, err := parser.ParseFile(token.NewFileSet(), "", before, parser.ParseComments)
+	if err != nil {
+		return
+	}
+	fa, err := parser.ParseFile(token.NewFileSet(), "", after, parser.ParseComments)
+	if err != nil {
+		return
+	}
+	old, renamed := sourceTexts(fb), sourceTexts(fa)
+	if len(old) != len(renamed) {
+		return // Not renderings of the same template.
+	}
+	conflicting := map[string]bool{}
+	for i, t := range old {
+		updated := *renamed[i].text
+		r.texts[t.key] = updated
+		if prev, ok := r.textValues[t.key.text]; ok && prev != updated {
+			conflicting[t.key.text] = true
+		}
+		r.textValues[t.key.text] = updated
+	}
+	for text := range conflicting {
+		delete(r.textValues, text)
+	}
+}
+
+// renameText returns the new form of a literal or comment, which is the old one unless the
+// templates render it differently for the new name.
+func (r *goRenamer) renameText(t sourceText) string {
+	if updated, ok := r.texts[t.key]; ok {
+		return updated
+	}
+	if updated, ok := r.textValues[t.key.text]; ok {
+		return updated
+	}
+	return *t.text
+}
+
+// sourceTexts lists the string literals and comments of f, except import paths and struct
+// tags, in source order.
+func sourceTexts(f *ast.File) []sourceText {
+	skip := map[*ast.BasicLit]bool{}
+	for _, imp := range f.Imports {
+		skip[imp.Path] = true
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if field, ok := n.(*ast.Field); ok && field.Tag != nil {
+			skip[field.Tag] = true
+		}
+		return true
+	})
+
+	seen := map[textKey]int{}
+	var texts []sourceText
+	add := func(pos token.Pos, text *string) {
+		key := textKey{decl: enclosingDecl(f, pos), text: *text}
+		n := seen[key]
+		seen[key]++
+		key.n = n
+		texts = append(texts, sourceText{key: key, text: text})
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING && !skip[lit] {
+			add(lit.Pos(), &lit.Value)
+		}
+		return true
+	})
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			add(c.Pos(), &c.Text)
+		}
+	}
+	return texts
+}
+
+// enclosingDecl names the top-level declaration of f that holds pos, with its doc comment.
+func enclosingDecl(f *ast.File, pos token.Pos) string {
+	for _, d := range f.Decls {
+		start := d.Pos()
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if pos < start || pos > d.End() {
+				continue
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				return types.ExprString(d.Recv.List[0].Type) + "." + d.Name.Name
+			}
+			return d.Name.Name
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if pos < start || pos > d.End() {
+				continue
+			}
+			if d.Tok == token.IMPORT {
+				return "import"
+			}
+			return strings.Join(specNames(d), ",")
+		}
+	}
+	return ""
+}
+
+// rewriteFile parses a Go file and applies the rename. When entityRefsOnly is set, only
+// selectors on the shared entities package (e.g. entities.Orders) are rewritten, which is
+// what files outside the renamed service need.
+func (r *goRenamer) rewriteFile(file string, entityRefsOnly bool) ([]byte, bool, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return r.rewriteSource(file, src, entityRefsOnly)
+}
+
+// rewriteSource is rewriteFile for source already in memory; file is used in errors only.
+func (r *goRenamer) rewriteSource(file string, src []byte, entityRefsOnly bool) ([]byte, bool, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse %s (fix syntax errors before renaming): %w", file, err)
+	}
+
+	var changed bool
+	if entityRefsOnly {
+		changed = r.rewriteEntityRefs(f)
+	} else {
+		changed = r.rewriteAll(f)
+	}
+	if !changed {
+		return src, false, nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, false, fmt.Errorf("failed to format %s: %w", file, err)
+	}
+	return buf.Bytes(), true, nil
+}
+
+// set assigns updated to *field and reports whether the value changed.
+func set(field *string, updated string) bool {
+	if *field == updated {
+		return false
+	}
+	*field = updated
+	return true
+}
+
+func (r *goRenamer) rewriteAll(f *ast.File) bool {
+	changed := false
+	if containsString(r.oldPkgs, f.Name.Name) {
+		changed = set(&f.Name.Name, r.newPkg) || changed
+	}
+
+	// Texts are looked up by their place in the file before any of them changes.
+	for _, t := range sourceTexts(f) {
+		changed = set(t.text, r.renameText(t)) || changed
+	}
+
+	imports := fileImports(f)
+	for _, imp := range f.Imports {
+		if p, err := strconv.Unquote(imp.Path.Value); err == nil && r.importPath(p) != p {
+			changed = set(&imp.Path.Value, strconv.Quote(r.importPath(p))) || changed
+		}
+	}
+
+	// Selected names are resolved against the package or value they are selected from;
+	// every other identifier, except package names, is renamed if the service declares it.
+	selected := map[*ast.Ident]bool{}
+	for _, imp := range f.Imports {
+		if imp.Name != nil {
+			selected[imp.Name] = true
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		selected[sel.Sel] = true
+		declared := r.members[sel.Sel.Name] && !r.externalValue(sel.X, imports)
+		if pkg, ok := selectedPackage(sel, imports); ok {
+			selected[sel.X.(*ast.Ident)] = true
+			key, own := r.ownPackage(pkg)
+			declared = own && r.decls[key][sel.Sel.Name]
+		}
+		if declared {
+			changed = set(&sel.Sel.Name, r.ident(sel.Sel.Name)) || changed
+		}
+		return true
+	})
+	ast.Inspect(f, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id != f.Name && !selected[id] && r.declared(id.Name) {
+			changed = set(&id.Name, r.ident(id.Name)) || changed
+		}
+		return true
+	})
+	return changed
+}
+
+// rewriteEntityRefs renames the selectors of the entity types, enums and constants that the
+// service declares in the shared entities package, e.g. entities.Orders, and nothing else:
+// entities.OrdersV2 of another service is left alone.
+// Other files of the entities package refer to them without the selector.
+func (r *goRenamer) rewriteEntityRefs(f *ast.File) bool {
+	entityPkgs := map[string]bool{}
+	for name, p := range fileImports(f) {
+		if key, _ := r.ownPackage(p); key == entitiesPackage {
+			entityPkgs[name] = true
+		}
+	}
+	inEntities := f.Name.Name == entitiesPackage
+	if len(entityPkgs) == 0 && !inEntities {
+		return false
+	}
+
+	changed := false
+	selected := map[*ast.Ident]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		selected[sel.Sel] = true
+		if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil && entityPkgs[x.Name] && r.decls[entitiesPackage][sel.Sel.Name] {
+			changed = set(&sel.Sel.Name, r.ident(sel.Sel.Name)) || changed
+		}
+		return true
+	})
+	if inEntities {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id != f.Name && !selected[id] && id.Obj == nil && r.decls[entitiesPackage][id.Name] {
+				changed = set(&id.Name, r.ident(id.Name)) || changed
+			}
+			return true
+		})
+	}
+	return changed
+}
+
+// declared reports whether the service's own code declares name.
+func (r *goRenamer) declared(name string) bool {
+	if r.members[name] || r.locals[name] {
+		return true
+	}
+	for _, names := range r.decls {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// ownPackage returns the goRenamer.decls key of an imported package that belongs to the
+// service or is the shared entities package.
+func (r *goRenamer) ownPackage(p string) (string, bool) {
+	switch {
+	case p == entitiesPackage || strings.HasSuffix(p, "/"+entitiesPackage):
+		return entitiesPackage, true
+	case r.oldModule != "" && (p == r.oldModule || strings.HasPrefix(p, r.oldModule+"/")):
+		return p, true
+	}
+	return "", false
+}
+
+// fileImports maps the names a file refers to its imports by to their paths.
+func fileImports(f *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, imp := range f.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importName(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = p
+	}
+	return imports
+}
+
+// importName guesses the package name of an import path without loading the package: its
+// last element, skipping a major version suffix, e.g. "fiber" for github.com/gofiber/fiber/v2.
+func importName(p string) string {
+	name := path.Base(p)
+	if major := strings.TrimPrefix(name, "v"); major != name && major != "" && strings.Trim(major, "0123456789") == "" && path.Dir(p) != "." {
+		name = path.Base(path.Dir(p))
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 && strings.Trim(name[i+2:], "0123456789") == "" { // gopkg.in/yaml.v3
+		name = name[:i]
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(name, ".go"), "go-")
+}
+
+// externalValue reports whether x is a variable declared with a type from a package that is
+// not the service's own, e.g. the ctx *fiber.Ctx parameter of a handler, whose fields and
+// methods the rename must leave alone even if the service declares members of the same name.
+func (r *goRenamer) externalValue(x ast.Expr, imports map[string]string) bool {
+	id, ok := x.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return false
+	}
+	var typ ast.Expr
+	switch decl := id.Obj.Decl.(type) {
+	case *ast.Field:
+		typ = decl.Type
+	case *ast.ValueSpec:
+		typ = decl.Type
+	}
+	for {
+		star, ok := typ.(*ast.StarExpr)
+		if !ok {
+			break
+		}
+		typ = star.X
+	}
+	sel, ok := typ.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := selectedPackage(sel, imports)
+	if !ok {
+		return false
+	}
+	_, own := r.ownPackage(pkg)
+	return !own
+}
+
+// selectedPackage returns the import path of the package sel selects from, if any.
+func selectedPackage(sel *ast.SelectorExpr, imports map[string]string) (string, bool) {
+	x, ok := sel.X.(*ast.Ident)
+	if !ok || x.Obj != nil { // Obj is set when a local declaration shadows the import.
+		return "", false
+	}
+	p, ok := imports[x.Name]
+	return p, ok
+}
+
+// ident renames occurrences of the old exported name inside an identifier, e.g.
+// NewOrdersService -> NewInvoicesService, and of its lowerCamel form at the start of one,
+// e.g. ordersController -> invoicesController. A match must not be followed by a
+// lower-case letter, so "Order" does not match inside "Orderly".
+func (r *goRenamer) ident(name string) string {
+	for _, old := range r.oldIdents {
+		name = replaceBounded(name, old, r.newIdent, func(prev, next rune) bool {
+			return next == 0 || !unicode.IsLower(next)
+		})
+	}
+	for _, old := range r.oldVars {
+		name = replaceBounded(name, old, r.newVar, func(prev, next rune) bool {
+			return !isWordRune(prev) && (next == 0 || !unicode.IsLower(next))
+		})
+	}
+	return name
+}
+
+func (r *goRenamer) importPath(p string) string {
+	if r.oldModule == "" || r.oldModule == r.newModule {
+		return p
+	}
+	if p == r.oldModule {
+		return r.newModule
+	}
+	if strings.HasPrefix(p, r.oldModule+"/") {
+		return r.newModule + strings.TrimPrefix(p, r.oldModule)
+	}
+	return p
+}
+
+// replaceWord replaces whole-word occurrences of old in s. The plural form generated for
+// route paths ("/orders" for service "order") also counts as a whole word.
+func replaceWord(s, old, new string) string {
+	return replaceBounded(s, old, new, func(prev, next rune) bool {
+		return !isWordRune(prev) && !isWordRune(next)
+	})
+}
+
+// replaceBounded replaces the occurrences of old in s for which ok accepts the runes
+// immediately before and after the match (0 at the string boundaries). A trailing plural
+// "s" is skipped over when checking the following rune.
+func replaceBounded(s, old, new string, ok func(prev, next rune) bool) string {
+	if old == "" || !strings.Contains(s, old) {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		var prev, next rune
+		if i > 0 {
+			prev = lastRune(s[:i])
+		}
+		rest := s[i+len(old):]
+		if rest != "" {
+			next = firstRune(rest)
+			if next == 's' && (len(rest) == 1 || !isWordRune(firstRune(rest[1:]))) {
+				next = 0
+				if len(rest) > 1 {
+					next = firstRune(rest[1:])
+				}
+			}
+		}
+		b.WriteString(s[:i])
+		if ok(prev, next) {
+			b.WriteString(new)
+		} else {
+			b.WriteString(old)
+		}
+		s = rest
+	}
+}
+
+// lowerFirst lower-cases the first letter of an identifier, e.g. "OrderItems" -> "orderItems".
+func lowerFirst(s string) string {
+	for i, r := range s {
+		return string(unicode.ToLower(r)) + s[i+utf8.RuneLen(r):]
+	}
+	return s
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+	return runes[len(runes)-1]
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
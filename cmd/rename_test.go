@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// runRename runs 'gores rename oldName newName'.
+func runRename(t *testing.T, oldName, newName string) {
+	t.Helper()
+	if err := renameCmd.RunE(renameCmd, []string{oldName, newName}); err != nil {
+		t.Fatalf("rename %s %s: %v", oldName, newName, err)
+	}
+}
+
+// upgradeOutcomes returns the outcome 'gores upgrade target' would have for every file
+// that it would change.
+func upgradeOutcomes(t *testing.T, target string) map[string]string {
+	t.Helper()
+	m, err := LoadManifest(ManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := renderUpgradeTarget(m, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := map[string]string{}
+	for _, f := range rendered {
+		if r := upgradeFile(idx, f, mergeLabels{}); r.outcome != upgradeUnchanged {
+			changes[filepath.ToSlash(r.file)] = r.outcome
+		}
+	}
+	return changes
+}
+
+func TestReplaceBounded(t *testing.T) {
+	upperFollows := func(prev, next rune) bool { return next == 0 || !unicode.IsLower(next) }
+	for _, tt := range []struct {
+		s, old, new string
+		ok          func(prev, next rune) bool
+		want        string
+	}{
+		{"/orders/:id", "order", "invoice", nil, "/invoices/:id"},
+		{"orders-service", "orders", "invoices", nil, "invoices-service"},
+		{"reorder the orderly orders", "order", "invoice", nil, "reorder the orderly invoices"},
+		{"order_items", "order", "invoice", nil, "order_items"},
+		{"NewOrdersService", "Orders", "Invoices", upperFollows, "NewInvoicesService"},
+		{"Orderly", "Order", "Invoice", upperFollows, "Orderly"},
+		{"OrderService", "Order", "Invoice", upperFollows, "InvoiceService"},
+		{"", "order", "invoice", nil, ""},
+	} {
+		var got string
+		if tt.ok == nil {
+			got = replaceWord(tt.s, tt.old, tt.new)
+		} else {
+			got = replaceBounded(tt.s, tt.old, tt.new, tt.ok)
+		}
+		if got != tt.want {
+			t.Errorf("replacing %q in %q = %q, want %q", tt.old, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestGoRenamerIdent(t *testing.T) {
+	r := newGoRenamer("order-items", "invoice-lines", "", "")
+	for name, want := range map[string]string{
+		"OrderItems":                    "InvoiceLines",
+		"NewOrderItemsService":          "NewInvoiceLinesService",
+		"OrderItemsRequest":             "InvoiceLinesRequest",
+		"orderItemsService":             "invoiceLinesService",
+		"orderItemsController":          "invoiceLinesController",
+		"orderItems":                    "invoiceLines",
+		"OrderItemsly":                  "OrderItemsly",
+		"reorderItemsService":           "reorderItemsService",
+		"NewMemoryOrderItemsRepository": "NewMemoryInvoiceLinesRepository",
+	} {
+		if got := r.ident(name); got != want {
+			t.Errorf("ident(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// renamerTemplate stands in for a generated file; renderRenamerTemplate fills in a name.
+const renamerTemplate = `package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gores/pkg/entities"
+	"gores/services/{{name}}/internal"
+)
+
+// {{var}}Service serves the /{{name}}s routes.
+var {{var}}Service = internal.New{{Type}}Service(nil)
+
+// healthCheck reports that the {{name}} service is up.
+func healthCheck(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "{{name}}"})
+}
+
+func find(items []entities.{{Type}}) *entities.{{Type}} { return &items[0] }
+`
+
+func renderRenamerTemplate(name string) string {
+	return strings.NewReplacer("{{name}}", name, "{{Type}}", toPascalCase(name), "{{var}}", lowerFirst(toPascalCase(name))).Replace(renamerTemplate)
+}
+
+// newStatusRenamer renames the service "status", whose name appears inside the identifiers of
+// net/http, to "state". It has learned the rendering of renamerTemplate.
+func newStatusRenamer(t *testing.T) *goRenamer {
+	t.Helper()
+	r := newGoRenamer("status", "state", "gores/services/status", "gores/services/state")
+	r.learn([]byte(renderRenamerTemplate("status")), []byte(renderRenamerTemplate("state")))
+	for pkg, src := range map[string]string{
+		entitiesPackage:                  "package entities\n\ntype Status struct{ Code int }\n",
+		"gores/services/status/internal": "package internal\n\nfunc NewStatusService(any) any { return nil }\n",
+	} {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.declare(pkg, f)
+	}
+	return r
+}
+
+func TestGoRenamerRewritesSource(t *testing.T) {
+	r := newStatusRenamer(t)
+	// Code added by hand: its identifiers are renamed when the service declares them, and its
+	// literals and comments are left alone.
+	const edits = `
+// statusCode returns the HTTP status of a response.
+func statusCode(resp *http.Response) int {
+	status := resp.StatusCode
+	return status
+}
+
+type result struct {
+	Status entities.Status ` + "`json:\"status\"`" + `
+}
+
+func code() int { return statusCode(&http.Response{StatusCode: http.StatusOK}) + result{}.Status.Code }
+`
+	src := renderRenamerTemplate("status") + edits
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.declare("gores/services/status", f)
+
+	got, changed, err := r.rewriteSource("main.go", []byte(src), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := renderRenamerTemplate("state") + strings.NewReplacer(
+		"func statusCode", "func stateCode",
+		"status := ", "state := ",
+		"return status", "return state",
+		"Status entities.Status", "State entities.State",
+		"return statusCode", "return stateCode",
+		"result{}.Status", "result{}.State",
+	).Replace(edits)
+	if !changed || string(got) != want {
+		t.Errorf("rewriteSource(changed=%v) =\n%s\nwant:\n%s", changed, got, want)
+	}
+
+	// Outside the service only the references to the entities it declares change.
+	other := `package payments
+
+import "gores/pkg/entities"
+
+// status is not derived from the renamed service here.
+var status []entities.Status
+
+var statusV2 []entities.StatusV2
+`
+	got, _, err = r.rewriteSource("payments.go", []byte(other), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(other, "entities.Status\n", "entities.State\n", 1); string(got) != want {
+		t.Errorf("rewriteSource(entityRefsOnly) =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestImportName(t *testing.T) {
+	for p, want := range map[string]string{
+		"net/http":                    "http",
+		"github.com/gofiber/fiber/v2": "fiber",
+		"gopkg.in/yaml.v3":            "yaml",
+		"github.com/go-chi/chi/v5":    "chi",
+		"gores/pkg/entities":          "entities",
+	} {
+		if got := importName(p); got != want {
+			t.Errorf("importName(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestPlanRename(t *testing.T) {
+	newOrdersProject(t)
+	m, err := LoadManifest(ManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainFile := filepath.Join(servicesDir, "orders", "cmd", "main.go")
+	before, err := os.ReadFile(mainFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := planRename(m, "orders", "invoices")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is written while planning.
+	if after, _ := os.ReadFile(mainFile); string(after) != string(before) {
+		t.Errorf("planRename changed %s", mainFile)
+	}
+	main := string(plan.writes[mainFile])
+	for _, want := range []string{`"gores/services/invoices/internal"`, "invoicesService", "invoicesController", "NewInvoicesService"} {
+		if !strings.Contains(main, want) {
+			t.Errorf("planned %s lacks %q:\n%s", mainFile, want, main)
+		}
+	}
+	if goMod := string(plan.writes[filepath.Join(servicesDir, "orders", "go.mod")]); !strings.HasPrefix(goMod, "module gores/services/invoices\n") {
+		t.Errorf("planned go.mod:\n%s", goMod)
+	}
+	if plan.oldEntity != entityFilePath("orders", TemplateGeneric) || plan.newEntity != entityFilePath("invoices", TemplateGeneric) {
+		t.Errorf("entity file moves from %s to %s", plan.oldEntity, plan.newEntity)
+	}
+	if !strings.Contains(string(plan.entityContent), "type Invoices struct") {
+		t.Errorf("planned entity:\n%s", plan.entityContent)
+	}
+	if len(plan.moves) != 2 || plan.moves[0].to != clientFiles("invoices")[0] || !strings.HasPrefix(string(plan.moves[0].content), "// Package invoices") {
+		t.Errorf("client moves: %+v", plan.moves)
+	}
+	if _, ok := plan.baselines[baselineKey(filepath.Join(servicesDir, "orders", "internal", "openapi.yaml"))]; !ok {
+		t.Errorf("openapi.yaml is not rendered again")
+	}
+
+	if _, err := planRename(m, "billing", "invoices"); err == nil {
+		t.Errorf("planning the rename of an unregistered service succeeded")
+	}
+}
+
+func TestUpgradeAfterRenameIsNoOp(t *testing.T) {
+	newOrdersProject(t)
+
+	runRename(t, "orders", "invoices")
+
+	for _, target := range []string{pkgTarget, "invoices"} {
+		if changes := upgradeOutcomes(t, target); len(changes) > 0 {
+			t.Errorf("upgrade %s after rename would change files: %v", target, changes)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(servicesDir, "orders")); !os.IsNotExist(err) {
+		t.Errorf("services/orders still exists (err: %v)", err)
+	}
+}
+
+func TestRenameRendersOpenAPIForNewName(t *testing.T) {
+	newOrdersProject(t)
+	spec := filepath.Join(servicesDir, "orders", "internal", "openapi.yaml")
+	content, err := os.ReadFile(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An edit of the spec survives the rename.
+	if err := os.WriteFile(spec, append(content, "# Reviewed by the API team.\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runRename(t, "orders", "invoices")
+
+	content, err = os.ReadFile(filepath.Join(servicesDir, "invoices", "internal", "openapi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, old := range []string{"orders", "Orders"} {
+		if strings.Contains(string(content), old) {
+			t.Errorf("openapi.yaml still mentions %q:\n%s", old, content)
+		}
+	}
+	for _, want := range []string{"/invoicess:", "operationId: listInvoices", "# Reviewed by the API team."} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("openapi.yaml lacks %q:\n%s", want, content)
+		}
+	}
+	if outcome, ok := upgradeOutcomes(t, "invoices")["services/invoices/internal/openapi.yaml"]; ok {
+		t.Errorf("upgrade after rename would leave openapi.yaml %s", outcome)
+	}
+}
+
+func TestRenameKeepsServiceBuilding(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated service")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go tool is not installed")
+	}
+	// "Status" is also part of http.StatusOK and fiber's Ctx.Status.
+	newServicesProject(t, "status")
+	if out, err := runOfflineGo(filepath.Join(servicesDir, "status"), "vet", "./..."); err != nil {
+		t.Skipf("the generated service does not build from the local module cache: %v\n%s", err, out)
+	}
+
+	runRename(t, "status", "state")
+
+	if out, err := runOfflineGo(filepath.Join(servicesDir, "state"), "vet", "./..."); err != nil {
+		t.Fatalf("the renamed service no longer builds: %v\n%s", err, out)
+	}
+	router, err := os.ReadFile(filepath.Join(servicesDir, "state", "internal", "controller.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"StateController", "fiber.StatusOK", `"status":`} {
+		if !strings.Contains(string(router), want) {
+			t.Errorf("the renamed controller lacks %q:\n%s", want, router)
+		}
+	}
+}
+
+func TestRenameLeavesSiblingEntities(t *testing.T) {
+	// The entity of orders-v2, entities.OrdersV2, starts with the renamed one's.
+	newServicesProject(t, "orders", "orders-v2")
+	sibling := filepath.Join(servicesDir, "orders-v2", "internal", "service.go")
+	before, err := os.ReadFile(sibling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(before), "entities.OrdersV2") {
+		t.Fatalf("%s does not refer to entities.OrdersV2:\n%s", sibling, before)
+	}
+
+	runRename(t, "orders", "invoices")
+
+	if after, _ := os.ReadFile(sibling); string(after) != string(before) {
+		t.Errorf("rename changed %s:\n%s", sibling, after)
+	}
+	if entity, err := os.ReadFile(entityFilePath("orders-v2", TemplateGeneric)); err != nil || !strings.Contains(string(entity), "type OrdersV2 struct") {
+		t.Errorf("the entity of orders-v2 changed (err: %v):\n%s", err, entity)
+	}
+}
+
+func TestReplacePathPrefix(t *testing.T) {
+	for s, want := range map[string]string{
+		"./services/orders":                  "./services/invoices",
+		"services/orders/Dockerfile":         "services/invoices/Dockerfile",
+		"services/orders-v2/Dockerfile":      "services/orders-v2/Dockerfile",
+		"services/ordersmith":                "services/ordersmith",
+		"services/orders-v2:services/orders": "services/orders-v2:services/invoices",
+	} {
+		if got := replacePathPrefix(s, "services/orders", "services/invoices"); got != want {
+			t.Errorf("replacePathPrefix(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestRenameComposeLeavesSiblingService(t *testing.T) {
+	newServicesProject(t, "orders", "orders-v2")
+	const compose = `services:
+  orders:
+    build:
+      context: .
+      dockerfile: services/orders/Dockerfile
+  orders-v2:
+    build:
+      context: .
+      dockerfile: services/orders-v2/Dockerfile
+    depends_on:
+      - orders
+`
+	if err := os.WriteFile("docker-compose.yml", []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runRename(t, "orders", "invoices")
+
+	content, err := os.ReadFile("docker-compose.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer(
+		"  orders:\n", "  invoices:\n",
+		"services/orders/Dockerfile", "services/invoices/Dockerfile",
+		"- orders\n", "- invoices\n",
+	).Replace(compose)
+	if string(content) != want {
+		t.Errorf("docker-compose.yml =\n%s\nwant:\n%s", content, want)
+	}
+}
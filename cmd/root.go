@@ -7,10 +7,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Version is the gores release version. It is overridden at build time with
+// -ldflags "-X github.com/Muhammad-Ali-Khan9/gores/cmd.Version=vX.Y.Z".
+var Version = "dev"
+
 var rootCmd = &cobra.Command{
-	Use:   "gores",
-	Short: "Go Microservice Boilerplate Generator CLI",
-	Long:  "A CLI tool to generate Go microservice boilerplate code with controllers, services, entities, routers, and more.",
+	Use:     "gores",
+	Version: Version,
+	Short:   "Go Microservice Boilerplate Generator CLI",
+	Long:    "A CLI tool to generate Go microservice boilerplate code with controllers, services, entities, routers, and more.",
 }
 
 func Execute() {
@@ -18,4 +23,4 @@ func Execute() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,583 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CRUD operations an entity can expose over HTTP.
+const (
+	OperationList   = "list"
+	OperationGet    = "get"
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+var entityOperations = []string{OperationList, OperationGet, OperationCreate, OperationUpdate, OperationDelete}
+
+// SchemaFile is an entity definition file read by 'gores generate --from'. JSON files are
+// accepted as well, since YAML is a superset of JSON.
+type SchemaFile struct {
+	Service  string         `yaml:"service,omitempty"` // Defaults to the service-name argument or the file name
+	Port     int            `yaml:"port,omitempty"`
+	Entities []SchemaEntity `yaml:"entities"`
+}
+
+// SchemaEntity declares one entity of a service. It is also how schema-driven services
+// record their entities in gores.yaml.
+type SchemaEntity struct {
+	Name      string           `yaml:"name"`
+	Fields    []SchemaField    `yaml:"fields,omitempty"`
+	Relations []SchemaRelation `yaml:"relations,omitempty"`
+	API       *SchemaAPI       `yaml:"api,omitempty"`
+}
+
+// SchemaAPI controls how an entity is exposed over HTTP. Omitted, every operation is
+// served under the default path.
+type SchemaAPI struct {
+	Expose     *bool    `yaml:"expose,omitempty"`     // false generates the entity and its service only
+	Path       string   `yaml:"path,omitempty"`       // Route base path, default "/<name>s"
+	Operations []string `yaml:"operations,omitempty"` // Subset of list, get, create, update and delete
+}
+
+// SchemaField is a field spec in the --field syntax. In a schema file it may also be
+// written as a mapping, e.g. {name: status, type: enum, values: [pending, paid], required: true}.
+type SchemaField string
+
+// UnmarshalYAML accepts both the spec string and the mapping form of a field.
+func (f *SchemaField) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var spec string
+		if err := node.Decode(&spec); err != nil {
+			return err
+		}
+		*f = SchemaField(spec)
+		return nil
+	}
+
+	var m struct {
+		Name     string   `yaml:"name"`
+		Type     string   `yaml:"type"`
+		Values   []string `yaml:"values"`
+		Optional bool     `yaml:"optional"`
+		Nullable bool     `yaml:"nullable"`
+		Required bool     `yaml:"required"`
+		Unique   bool     `yaml:"unique"`
+		Indexed  bool     `yaml:"indexed"`
+	}
+	if err := node.Decode(&m); err != nil {
+		return err
+	}
+	kind := m.Type
+	if len(m.Values) > 0 {
+		kind = fmt.Sprintf("%s(%s)", m.Type, strings.Join(m.Values, ","))
+	}
+	parts := []string{m.Name, kind}
+	for _, mod := range []struct {
+		set  bool
+		name string
+	}{{m.Optional || m.Nullable, ModifierOptional}, {m.Required, ModifierRequired}, {m.Unique, ModifierUnique}, {m.Indexed, ModifierIndexed}} {
+		if mod.set {
+			parts = append(parts, mod.name)
+		}
+	}
+	*f = SchemaField(strings.Join(parts, ":"))
+	return nil
+}
+
+// SchemaRelation is a relation spec in the --relation syntax, or a mapping such as
+// {name: items, type: has-many, entity: line-items}.
+type SchemaRelation string
+
+// UnmarshalYAML accepts both the spec string and the mapping form of a relation.
+func (r *SchemaRelation) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var spec string
+		if err := node.Decode(&spec); err != nil {
+			return err
+		}
+		*r = SchemaRelation(spec)
+		return nil
+	}
+
+	var m struct {
+		Name     string `yaml:"name"`
+		Type     string `yaml:"type"`
+		Entity   string `yaml:"entity"`
+		Optional bool   `yaml:"optional"`
+	}
+	if err := node.Decode(&m); err != nil {
+		return err
+	}
+	spec := strings.Join([]string{m.Name, m.Type, m.Entity}, ":")
+	if m.Optional {
+		spec += ":" + ModifierOptional
+	}
+	*r = SchemaRelation(spec)
+	return nil
+}
+
+// EntitySpec is a resolved entity: its typed fields, relations and HTTP exposure.
+type EntitySpec struct {
+	Name        string // As declared, e.g. "line-items"; also the entity file name
+	Fields      []EntityField
+	Relations   []EntityRelation
+	ForeignKeys []EntityField // Foreign key columns added by relations, see linkEntities
+	Expose      bool
+	Path        string
+	Operations  []string
+	Proto       ProtoNumbers // Field numbers recorded for the messages of a gRPC service
+}
+
+// Type is the Go type name of the entity, e.g. "LineItems".
+func (e EntitySpec) Type() string {
+	return toPascalCase(e.Name)
+}
+
+// Table is the database table of the entity, pluralized the way gorm names tables, e.g.
+// "line_items". The entity's TableName method pins it, so migrations and model agree.
+func (e EntitySpec) Table() string {
+	return pluralize(snakeCase(e.Type()))
+}
+
+// pluralize applies the common English plural rules to a snake_case name.
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"):
+		return name
+	case strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou_", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
+
+// Var is a lowerCamelCase identifier derived from the entity, e.g. "lineItems".
+func (e EntitySpec) Var() string {
+	t := e.Type()
+	return strings.ToLower(t[:1]) + t[1:]
+}
+
+// Exposes reports whether the entity serves the given CRUD operation.
+func (e EntitySpec) Exposes(op string) bool {
+	return e.Expose && containsString(e.Operations, op)
+}
+
+// AllFields returns the declared fields followed by the foreign keys.
+func (e EntitySpec) AllFields() []EntityField {
+	return append(append([]EntityField{}, e.Fields...), e.ForeignKeys...)
+}
+
+// HasFieldKind reports whether any field of the entity is of the given kind.
+func (e EntitySpec) HasFieldKind(kind string) bool {
+	return fieldKindsUsed(e.AllFields())[kind]
+}
+
+// RequiredColumns returns the JSON names of the required fields, which request bodies
+// must set.
+func (e EntitySpec) RequiredColumns() []string {
+	var columns []string
+	for _, f := range e.AllFields() {
+		if f.Required {
+			columns = append(columns, f.Column())
+		}
+	}
+	return columns
+}
+
+// SampleFields returns the fields the generated tests set: every field that is not
+// optional, which includes the required ones.
+func (e EntitySpec) SampleFields() []EntityField {
+	var fields []EntityField
+	for _, f := range e.AllFields() {
+		if !f.Optional {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// SampleHasFieldKind reports whether any of the SampleFields is of the given kind.
+func (e EntitySpec) SampleHasFieldKind(kind string) bool {
+	return fieldKindsUsed(e.SampleFields())[kind]
+}
+
+// SampleJSON is a request body setting every field of SampleFields to its SampleJSON.
+func (e EntitySpec) SampleJSON() string {
+	members := make([]string, 0, len(e.Fields))
+	for _, f := range e.SampleFields() {
+		members = append(members, fmt.Sprintf("%q:%s", f.Column(), f.SampleJSON()))
+	}
+	return "{" + strings.Join(members, ",") + "}"
+}
+
+// GraphQLSampleInput is the GraphQL input object of SampleJSON, as sent in query variables.
+func (e EntitySpec) GraphQLSampleInput() string {
+	members := make([]string, 0, len(e.Fields))
+	for _, f := range e.SampleFields() {
+		members = append(members, fmt.Sprintf("%q:%s", f.GraphQLName(), f.GraphQLSampleJSON()))
+	}
+	return "{" + strings.Join(members, ",") + "}"
+}
+
+// CollectionRelations returns the has-many and many-to-many relations, which get nested routes.
+func (e EntitySpec) CollectionRelations() []EntityRelation {
+	var relations []EntityRelation
+	for _, r := range e.Relations {
+		if r.IsCollection() {
+			relations = append(relations, r)
+		}
+	}
+	return relations
+}
+
+// RelationNames returns the JSON names of the relations, as accepted by ?include=.
+func (e EntitySpec) RelationNames() []string {
+	names := make([]string, 0, len(e.Relations))
+	for _, r := range e.Relations {
+		names = append(names, r.JSONName())
+	}
+	return names
+}
+
+// newEntitySpec returns an entity exposing every operation under its default path.
+func newEntitySpec(name string, fields []EntityField) EntitySpec {
+	return EntitySpec{Name: name, Fields: fields, Expose: true, Path: defaultEntityPath(name), Operations: entityOperations}
+}
+
+func defaultEntityPath(name string) string {
+	return "/" + strings.ToLower(name) + "s"
+}
+
+// resolve validates a declared entity and parses its fields.
+func (e SchemaEntity) resolve() (EntitySpec, error) {
+	if !serviceNamePattern.MatchString(e.Name) {
+		return EntitySpec{}, fmt.Errorf("invalid entity name '%s': use letters, digits, '-' and '_', starting with a letter", e.Name)
+	}
+	specs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		specs = append(specs, string(f))
+	}
+	fields, err := parseFieldSpecs(toPascalCase(e.Name), specs)
+	if err != nil {
+		return EntitySpec{}, fmt.Errorf("entity '%s': %w", e.Name, err)
+	}
+	relationSpecs := make([]string, 0, len(e.Relations))
+	for _, r := range e.Relations {
+		relationSpecs = append(relationSpecs, string(r))
+	}
+	relations, err := parseRelationSpecs(toPascalCase(e.Name), relationSpecs)
+	if err != nil {
+		return EntitySpec{}, fmt.Errorf("entity '%s': %w", e.Name, err)
+	}
+	spec := newEntitySpec(e.Name, fields)
+	spec.Relations = relations
+	if e.API == nil {
+		return spec, nil
+	}
+	if e.API.Expose != nil {
+		spec.Expose = *e.API.Expose
+	}
+	if e.API.Path != "" {
+		if !strings.HasPrefix(e.API.Path, "/") || strings.ContainsAny(e.API.Path, " :*") {
+			return EntitySpec{}, fmt.Errorf("entity '%s': invalid API path '%s': must start with '/' and contain no spaces or route parameters", e.Name, e.API.Path)
+		}
+		spec.Path = strings.TrimSuffix(e.API.Path, "/")
+	}
+	if len(e.API.Operations) > 0 {
+		spec.Operations = nil
+		for _, op := range e.API.Operations {
+			if !containsString(entityOperations, op) {
+				return EntitySpec{}, fmt.Errorf("entity '%s': unknown API operation '%s'; expected %s", e.Name, op, strings.Join(entityOperations, ", "))
+			}
+			spec.Operations = append(spec.Operations, op)
+		}
+	}
+	return spec, nil
+}
+
+// canonicalSchemaEntity is the form recorded in gores.yaml: field specs in canonical order
+// and defaults left out.
+func canonicalSchemaEntity(spec EntitySpec) SchemaEntity {
+	e := SchemaEntity{Name: spec.Name}
+	for _, f := range spec.Fields {
+		e.Fields = append(e.Fields, SchemaField(f.String()))
+	}
+	for _, r := range spec.Relations {
+		e.Relations = append(e.Relations, SchemaRelation(r.String()))
+	}
+	api := SchemaAPI{}
+	if !spec.Expose {
+		api.Expose = &spec.Expose
+	}
+	if spec.Path != defaultEntityPath(spec.Name) {
+		api.Path = spec.Path
+	}
+	if len(spec.Operations) != len(entityOperations) {
+		api.Operations = spec.Operations
+	}
+	if api.Expose != nil || api.Path != "" || api.Operations != nil {
+		e.API = &api
+	}
+	return e
+}
+
+// loadSchemaFile reads and validates a schema file. The service name is taken from the
+// file, then from serviceName, then from the file name.
+func loadSchemaFile(path, serviceName string) (*SchemaFile, []EntitySpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+	var schema SchemaFile
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&schema); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse schema file %s: %w", path, err)
+	}
+
+	switch {
+	case schema.Service == "" && serviceName != "":
+		schema.Service = serviceName
+	case schema.Service == "":
+		schema.Service = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	case serviceName != "" && serviceName != schema.Service:
+		return nil, nil, fmt.Errorf("schema file %s describes service '%s', not '%s'", path, schema.Service, serviceName)
+	}
+	if !serviceNamePattern.MatchString(schema.Service) {
+		return nil, nil, fmt.Errorf("invalid service name '%s' in %s: use letters, digits, '-' and '_', starting with a letter", schema.Service, path)
+	}
+	if len(schema.Entities) == 0 {
+		return nil, nil, fmt.Errorf("schema file %s declares no entities", path)
+	}
+
+	var entities []EntitySpec
+	seen := map[string]string{}
+	exposed := false
+	for _, e := range schema.Entities {
+		spec, err := e.resolve()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if prev, ok := seen[spec.Type()]; ok {
+			return nil, nil, fmt.Errorf("%s: entities '%s' and '%s' would both be named %s", path, prev, spec.Name, spec.Type())
+		}
+		seen[spec.Type()] = spec.Name
+		exposed = exposed || spec.Expose
+		entities = append(entities, spec)
+	}
+	if !exposed {
+		return nil, nil, fmt.Errorf("%s: at least one entity must be exposed over the API", path)
+	}
+	if err := linkEntities(entities); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &schema, entities, nil
+}
+
+// serviceEntities returns the entities of a generic service: those recorded from its
+// schema file, or a single entity named after the service with its --field and
+// --relation specs.
+func serviceEntities(s *ServiceEntry) ([]EntitySpec, error) {
+	declared := s.Entities
+	if len(declared) == 0 {
+		e := SchemaEntity{Name: s.Name}
+		for _, f := range s.Fields {
+			e.Fields = append(e.Fields, SchemaField(f))
+		}
+		for _, r := range s.Relations {
+			e.Relations = append(e.Relations, SchemaRelation(r))
+		}
+		declared = []SchemaEntity{e}
+	}
+	entities := make([]EntitySpec, 0, len(declared))
+	for _, e := range declared {
+		spec, err := e.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("invalid entity recorded for '%s' in %s: %w", s.Name, ManifestFile, err)
+		}
+		entities = append(entities, spec)
+	}
+	if err := linkEntities(entities); err != nil {
+		return nil, fmt.Errorf("invalid entity recorded for '%s' in %s: %w", s.Name, ManifestFile, err)
+	}
+	for i := range entities {
+		entities[i].Proto = s.ProtoFields[entities[i].Name]
+	}
+	return entities, nil
+}
+
+// serviceEntityFiles returns the shared entity files owned by a service.
+func serviceEntityFiles(s *ServiceEntry) []string {
+	if s.Template == TemplateAuth {
+		return []string{entityFilePath(s.Name, s.Template)}
+	}
+	files := []string{entityFilePath(s.Name, s.Template)}
+	for _, e := range s.Entities {
+		if file := entityFilePath(e.Name, s.Template); !containsString(files, file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// checkEntityReferences fails if one of the entities would take over another service's
+// entity type in the shared pkg/entities package, or relates to an entity that exists
+// neither in the service nor elsewhere in the project, or only outside a SQL database.
+func checkEntityReferences(m *Manifest, serviceName string, entities []EntitySpec) error {
+	owners := map[string]string{"User": authServiceName}
+	nonSQL := map[string]bool{} // Entity types of services that do not use a SQL database
+	for i := range m.Services {
+		s := &m.Services[i]
+		if s.Name == serviceName {
+			continue
+		}
+		if s.Template == TemplateAuth {
+			owners["User"] = s.Name
+			continue
+		}
+		others, err := serviceEntities(s)
+		if err != nil {
+			return err
+		}
+		for _, e := range others {
+			owners[e.Type()] = s.Name
+			if !sqlDatabase(m.ServiceDatabase(s)) {
+				nonSQL[e.Type()] = true
+			}
+		}
+	}
+	own := map[string]bool{}
+	for _, e := range entities {
+		if owner, ok := owners[e.Type()]; ok {
+			return fmt.Errorf("entity '%s' clashes with entities.%s of service '%s'", e.Name, e.Type(), owner)
+		}
+		own[e.Type()] = true
+	}
+	for _, e := range entities {
+		for _, r := range e.Relations {
+			if _, ok := owners[r.TargetType()]; !ok && !own[r.TargetType()] {
+				return fmt.Errorf("entity '%s': relation '%s' targets unknown entity '%s'", e.Name, r.Name, r.Target)
+			}
+			if nonSQL[r.TargetType()] && !own[r.TargetType()] {
+				return fmt.Errorf("entity '%s': relation '%s' targets entity '%s' of service '%s', which does not use a SQL database", e.Name, r.Name, r.Target, owners[r.TargetType()])
+			}
+		}
+	}
+	return nil
+}
+
+// recordServiceEntities stores the entities of a service in its manifest entry: the schema
+// file and every entity for schema-driven services, the --field specs otherwise.
+func recordServiceEntities(s *ServiceEntry, schemaPath string, entities []EntitySpec) {
+	if schemaPath == "" {
+		s.Schema, s.Entities = "", nil
+		s.Fields = fieldSpecs(entities[0].Fields)
+		s.Relations = nil
+		for _, r := range entities[0].Relations {
+			s.Relations = append(s.Relations, r.String())
+		}
+		return
+	}
+	s.Schema, s.Fields, s.Relations, s.Entities = schemaPath, nil, nil, nil
+	for _, e := range entities {
+		s.Entities = append(s.Entities, canonicalSchemaEntity(e))
+	}
+}
+
+// regenerateService renders an existing service from its edited schema and three-way
+// merges the result into the generated files, keeping local edits the way 'gores upgrade'
+// does. It returns the regenerated files.
+func regenerateService(m *Manifest, entry *ServiceEntry, schemaPath string, entities []EntitySpec, dryRun bool) (generatedFiles, error) {
+	if entry.Template != TemplateGeneric {
+		return nil, fmt.Errorf("service '%s' uses template '%s' and cannot be regenerated from a schema", entry.Name, entry.Template)
+	}
+	if !ServiceExists(entry.Name) {
+		return nil, fmt.Errorf("service '%s' is registered in %s but %s does not exist", entry.Name, ManifestFile, filepath.Join(servicesDir, entry.Name))
+	}
+	if err := checkEntityReferences(m, entry.Name, entities); err != nil {
+		return nil, err
+	}
+	if err := checkServiceDatabase(m.ServiceDatabase(entry), entities); err != nil {
+		return nil, err
+	}
+	previous, err := serviceEntities(entry)
+	if err != nil {
+		return nil, err
+	}
+	// Fields keep their .proto numbers across schema changes.
+	for i := range entities {
+		for _, old := range previous {
+			if old.Name == entities[i].Name {
+				entities[i].Proto = old.protoNumbers()
+			}
+		}
+	}
+
+	mem := newMemFS()
+	files, err := createMicroservice(mem, serviceGeneratorOptions(m, entry), entry.Name, fmt.Sprint(entry.Port), "templates/", entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render templates for '%s': %w", entry.Name, err)
+	}
+	rendered, err := readRenderedFiles(mem, files)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		return nil, err
+	}
+	labels := mergeLabels{ours: "yours", base: "previous schema", theirs: schemaPath}
+	var results []upgradeResult
+	for _, f := range rendered {
+		results = append(results, upgradeFile(idx, f, labels))
+	}
+
+	// Files of entities dropped from the schema are left for the user to delete.
+	for _, old := range previous {
+		dropped := true
+		for _, e := range entities {
+			dropped = dropped && e.Name != old.Name
+		}
+		if dropped {
+			fmt.Fprintf(os.Stderr, "Warning: entity '%s' is no longer in %s; its files were left in place.\n", old.Name, schemaPath)
+		}
+	}
+
+	if dryRun {
+		printUpgradeSummary("Regeneration", results, true)
+		return files, nil
+	}
+	if err := applyUpgradeResults(results); err != nil {
+		return nil, err
+	}
+	if (TemplateData{Entities: entities}).HasFieldKind(FieldDecimal) {
+		if err := requirePkgDependency(osFS{}, "github.com/shopspring/decimal", "v1.4.0"); err != nil {
+			return nil, err
+		}
+	}
+	_, err = UpdateManifest(ManifestFile, func(m *Manifest) error {
+		if s := m.Service(entry.Name); s != nil {
+			recordServiceEntities(s, schemaPath, entities)
+			recordProtoNumbers(s, entities)
+			s.GoresVersion = Version
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("files were regenerated but updating %s failed: %w", ManifestFile, err)
+	}
+
+	if conflicts := printUpgradeSummary("Regeneration", results, false); conflicts > 0 {
+		return nil, fmt.Errorf("regeneration left conflicts in %d file(s); resolve the conflict markers and rebuild", conflicts)
+	}
+	return files, nil
+}
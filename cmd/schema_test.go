@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSchema(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSchemaFileJSON(t *testing.T) {
+	path := writeSchema(t, "billing.json", `{
+  "entities": [
+    {"name": "invoices", "fields": ["total:decimal:required", {"name": "state", "type": "enum", "values": ["open", "void"]}]},
+    {"name": "ledger", "api": {"expose": false}}
+  ]
+}`)
+	schema, entities, err := loadSchemaFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Service != "billing" {
+		t.Errorf("service = %q, want the file name", schema.Service)
+	}
+	if got := fieldSpecs(entities[0].Fields); !reflect.DeepEqual(got, []string{"total:decimal:required", "state:enum(open,void)"}) {
+		t.Errorf("fields = %v", got)
+	}
+	if entities[0].Path != "/invoicess" || !entities[0].Exposes(OperationDelete) {
+		t.Errorf("invoices should expose every operation under the default path, got %+v", entities[0])
+	}
+	if entities[1].Expose {
+		t.Error("ledger should not be exposed")
+	}
+
+	// The canonical form recorded in gores.yaml resolves to the same entities.
+	for _, e := range entities {
+		got, err := canonicalSchemaEntity(e).resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, e) {
+			t.Errorf("canonical form of %s resolves to %+v, want %+v", e.Name, got, e)
+		}
+	}
+}
+
+func TestLoadSchemaFileErrors(t *testing.T) {
+	cases := []struct {
+		name, service, content, want string
+	}{
+		{"unknown key", "", "entities:\n  - name: a\n    colour: red\n", "field colour not found"},
+		{"no entities", "", "service: a\n", "declares no entities"},
+		{"service mismatch", "b", "service: a\nentities:\n  - name: a\n", "describes service 'a', not 'b'"},
+		{"bad field", "", "entities:\n  - name: a\n    fields: [total:money]\n", "entity 'a': unknown type 'money'"},
+		{"bad operation", "", "entities:\n  - name: a\n    api: {operations: [patch]}\n", "unknown API operation 'patch'"},
+		{"bad path", "", "entities:\n  - name: a\n    api: {path: /a/:id}\n", "invalid API path"},
+		{"duplicate type", "", "entities:\n  - name: line-items\n  - name: line_items\n", "would both be named LineItems"},
+		{"nothing exposed", "", "entities:\n  - name: a\n    api: {expose: false}\n", "at least one entity must be exposed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := loadSchemaFile(writeSchema(t, "svc.yaml", tc.content), tc.service)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckEntityReferences(t *testing.T) {
+	m := NewManifest("")
+	m.Services = []ServiceEntry{
+		{Name: authServiceName, Template: TemplateAuth},
+		{Name: "orders", Template: TemplateGeneric, Entities: []SchemaEntity{{Name: "orders"}, {Name: "line-items"}}},
+	}
+	if err := checkEntityReferences(m, "billing", []EntitySpec{newEntitySpec("line_items", nil)}); err == nil {
+		t.Error("an entity named like another service's entity was accepted")
+	}
+	if err := checkEntityReferences(m, "user", []EntitySpec{newEntitySpec("user", nil)}); err == nil {
+		t.Error("an entity named like the auth service's User was accepted")
+	}
+	if err := checkEntityReferences(m, "orders", []EntitySpec{newEntitySpec("line-items", nil)}); err != nil {
+		t.Errorf("a service may keep its own entities: %v", err)
+	}
+
+	billing := newEntitySpec("billing", nil)
+	billing.Relations, _ = parseRelationSpecs("Billing", []string{"customer:belongs-to:user", "order:belongs-to:orders"})
+	if err := checkEntityReferences(m, "billing", []EntitySpec{billing}); err != nil {
+		t.Errorf("relations to the auth User and another service's entity were rejected: %v", err)
+	}
+	billing.Relations, _ = parseRelationSpecs("Billing", []string{"coupon:belongs-to:coupons"})
+	if err := checkEntityReferences(m, "billing", []EntitySpec{billing}); err == nil || !strings.Contains(err.Error(), "unknown entity 'coupons'") {
+		t.Errorf("a relation to a missing entity gave error %v", err)
+	}
+
+	m.Services = append(m.Services, ServiceEntry{Name: "coupons", Template: TemplateGeneric, Database: DatabaseNone})
+	if err := checkEntityReferences(m, "billing", []EntitySpec{billing}); err == nil || !strings.Contains(err.Error(), "does not use a SQL database") {
+		t.Errorf("a relation to an in-memory entity gave error %v", err)
+	}
+}
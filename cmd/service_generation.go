@@ -1,241 +1,440 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"go/format"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
+	"unicode"
+
+	"golang.org/x/mod/modfile"
 )
 
 type TemplateData struct {
-	Name    string
-	Port    string
-	RootDir string
+	Name          string       // Service name as given on the command line
+	Port          string       // Port assigned to the service in gores.yaml
+	Module        string       // Project module path from gores.yaml, e.g. "github.com/acme/platform"
+	PkgModule     string       // Module path of the shared pkg module, e.g. "github.com/acme/platform/pkg"
+	ServiceModule string       // Module path of the service, e.g. "github.com/acme/platform/services/orders"
+	Replace       bool         // Require the shared pkg module through a replace directive instead of go.work
+	Framework     string       // HTTP framework the service and the shared middleware are generated for, e.g. "chi"
+	API           string       // API the service serves its entities through: "http", "grpc" or "graphql"
+	Database      string       // Database the service persists its entities with, e.g. "postgres"
+	Databases     []string     // Databases of the whole project, which the shared pkg module connects to
+	IDStrategy    string       // How MongoDB services generate IDs: "objectid" or "uuid"
+	Entities      []EntitySpec // Every entity of the service
+	Entity        EntitySpec   // The entity a per-entity template is rendered for
 }
 
-func createAuthMicroservice(name, port string) error {
-	const templateRoot = "templates/auth/" // Hardcoded path for auth templates
+// HasFieldKind reports whether any field of any entity is of the given kind, so templates
+// can add the imports and requirements it needs.
+func (d TemplateData) HasFieldKind(kind string) bool {
+	for _, e := range d.Entities {
+		if e.HasFieldKind(kind) {
+			return true
+		}
+	}
+	return false
+}
 
-	serviceDirPath := filepath.Join("services", name)
-	internalDirPath := filepath.Join(serviceDirPath, "src/internal")
-	cmdDirPath := filepath.Join(serviceDirPath, "src/cmd")
+// UsesDatabase reports whether the project connects to the given database, so the shared
+// pkg module requires its driver.
+func (d TemplateData) UsesDatabase(name string) bool {
+	for _, db := range d.Databases {
+		if db == name {
+			return true
+		}
+	}
+	return false
+}
 
-	foldersToCreate := []string{
-		serviceDirPath,
-		internalDirPath,
-		cmdDirPath,
+// IDGormTag is the gorm tag of the ID column of the service's entities. Only PostgreSQL
+// generates UUIDs itself; elsewhere the service assigns them before inserting.
+func (d TemplateData) IDGormTag() string {
+	switch d.Database {
+	case DatabaseMySQL:
+		return "type:char(36);primaryKey"
+	case DatabaseSQLite:
+		return "type:text;primaryKey"
 	}
+	return "type:uuid;primaryKey;default:gen_random_uuid()"
+}
 
-	for _, folder := range foldersToCreate {
-		if err := os.MkdirAll(folder, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create folder %s: %w", folder, err)
+// ServicePath is the base path of the routes that belong to the whole service rather than
+// to one of its entities, such as its GraphQL endpoint or its OpenAPI spec, e.g. "/orders"
+// for the order service.
+func (d TemplateData) ServicePath() string {
+	return defaultEntityPath(d.Name)
+}
+
+// HasEntity reports whether the service declares an entity of the given Go type, so the
+// OpenAPI spec can reference its schema from the relations pointing at it.
+func (d TemplateData) HasEntity(typeName string) bool {
+	for _, e := range d.Entities {
+		if e.Type() == typeName {
+			return true
 		}
 	}
+	return false
+}
 
-	templates := map[string]string{
-		"main.tmpl":       filepath.Join(cmdDirPath, "main.go"),
-		"router.tmpl":     filepath.Join(internalDirPath, "router.go"),
-		"controller.tmpl": filepath.Join(internalDirPath, "controller.go"),
-		"service.tmpl":    filepath.Join(internalDirPath, "service.go"),
-		"go.mod.tmpl":     filepath.Join(serviceDirPath, "go.mod"),
-		"Dockerfile.tmpl": filepath.Join(serviceDirPath, "Dockerfile"),
-		"entity.tmpl": filepath.Join("pkg", "entities", "user.entity.go"),
-	}
+// ProtoPackage is the protobuf package of a gRPC service, e.g. "orders.v1".
+func (d TemplateData) ProtoPackage() string {
+	return protoPackageName(d.Name)
+}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %w", err)
+// ProtoGoPackage is the name of the Go package protoc generates from the service's .proto
+// file into gen/, e.g. "ordersv1".
+func (d TemplateData) ProtoGoPackage() string {
+	return protoGoPackageName(d.Name)
+}
+
+// generatorOptions are the project-wide settings that shape every generated module.
+type generatorOptions struct {
+	Module     string // Project module path from gores.yaml
+	Replace    bool   // Emit 'replace <module>/pkg => ../../pkg' in service go.mod files
+	Framework  string // HTTP framework of the project; empty means Fiber
+	API        string // API of the generated service; empty means HTTP
+	Database   string // Database of the generated service; empty means PostgreSQL
+	IDStrategy string // ID strategy of MongoDB services; empty means ObjectIDs
+}
+
+// projectGeneratorOptions returns the generator options of a project. Replace directives
+// are emitted when the project asks for them, and whenever there is no go.work to resolve
+// the shared pkg module instead.
+func projectGeneratorOptions(m *Manifest) generatorOptions {
+	_, err := os.Stat(goWorkFile)
+	return generatorOptions{
+		Module:    m.Module,
+		Replace:   m.Settings.ReplaceDirectives || err != nil,
+		Framework: m.Framework(),
+		Database:  m.DefaultDatabase(),
 	}
-	rootDir := filepath.Base(cwd) // This assumes 'gores' is the current working directory base name.
+}
 
+// serviceGeneratorOptions returns the generator options of a registered service, which
+// may use another database than the project default.
+func serviceGeneratorOptions(m *Manifest, entry *ServiceEntry) generatorOptions {
+	opts := projectGeneratorOptions(m)
+	opts.Database = m.ServiceDatabase(entry)
+	opts.IDStrategy = entry.IDStrategy
+	opts.API = entry.API
+	return opts
+}
+
+// newTemplateData derives the module paths of the shared pkg module and of the service
+// from the project module path, mirroring their directories in the monorepo.
+func newTemplateData(opts generatorOptions, name, port string) TemplateData {
 	data := TemplateData{
-		Name:    name,
-		Port:    port,
-		RootDir: rootDir,
+		Name:      name,
+		Port:      port,
+		Module:    opts.Module,
+		PkgModule: opts.Module + "/pkg",
+		Replace:   opts.Replace,
+		Framework: opts.Framework,
+		API:       opts.API,
+		Database:  opts.Database,
 	}
-
-	funcMap := template.FuncMap{
-		"lower": strings.ToLower,
-		"title": strings.Title,
+	if data.Framework == "" {
+		data.Framework = FrameworkFiber
 	}
-
-	for tmplFile, outputPath := range templates {
-		actualTemplatePath := templateRoot + tmplFile
-		if tmplFile == "entity.tmpl" {
-			actualTemplatePath = "templates/auth/" + tmplFile
+	if data.API == "" {
+		data.API = APIHTTP
+	}
+	if data.Database == "" {
+		data.Database = DatabasePostgres
+	}
+	if data.Database == DatabaseMongo {
+		data.IDStrategy = opts.IDStrategy
+		if data.IDStrategy == "" {
+			data.IDStrategy = IDStrategyObjectID
 		}
+	}
+	if name != "" {
+		data.ServiceModule = opts.Module + "/" + servicesDir + "/" + name
+	}
+	return data
+}
 
-		content, err := templatesFS.ReadFile(actualTemplatePath)
-		if err != nil {
-			return fmt.Errorf("failed to read template %s from %s: %w", tmplFile, actualTemplatePath, err)
-		}
+// generatedFiles maps every file written by a generator to the embedded template it was
+// rendered from, so build errors can be traced back to their source.
+type generatedFiles map[string]string
 
-		t, err := template.New(tmplFile).Funcs(funcMap).Parse(string(content))
-		if err != nil {
-			return fmt.Errorf("failed to parse template %s: %w", tmplFile, err)
-		}
+// templateFuncs are the helpers available to every embedded template.
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"title": toPascalCase,
+	"join":  strings.Join,
+	"add":   func(a, b int) int { return a + b },
+}
 
-		f, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %w", outputPath, err)
-		}
+// pkgTemplate is a templated file of the shared pkg module.
+type pkgTemplate struct {
+	template string
+	output   string
+}
 
-		err = t.Execute(f, data)
-		f.Close() // Close the file immediately after execution
-		if err != nil {
-			return fmt.Errorf("failed to execute template %s into %s: %w", tmplFile, outputPath, err)
+// sharedPkgTemplates lists the templated files of the shared pkg module that every
+// project gets; the connection packages of its databases are listed in databaseBackends.
+var sharedPkgTemplates = []pkgTemplate{
+	{"templates/pkg_go.mod.tmpl", filepath.Join("pkg", "go.mod")},
+	{clientsPkgTemplate, clientsPkgFile},
+}
+
+// grpcTemplates is the directory of the templates gRPC services are generated from.
+const grpcTemplates = "templates/grpc/"
+
+// graphqlTemplates is the directory of the framework-independent templates of GraphQL
+// services; each framework directory adds the graphql_router.tmpl that serves the schema.
+const graphqlTemplates = "templates/graphql/"
+
+// middlewarePkgFile is the shared HTTP middleware, rendered from the middleware.tmpl of
+// the project's framework.
+var middlewarePkgFile = filepath.Join("pkg", "http", "middleware", "middleware.go")
+
+// toPascalCase converts a service name such as "order-items" into an exported Go
+// identifier ("OrderItems").
+func toPascalCase(name string) string {
+	var b strings.Builder
+	upperNext := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
 		}
-		fmt.Printf("Generated: %s\n", outputPath)
+		b.WriteRune(r)
 	}
+	return b.String()
+}
 
-	goSumPath := filepath.Join(serviceDirPath, "go.sum")
-	if _, err := os.Stat(goSumPath); os.IsNotExist(err) {
-		if err := os.WriteFile(goSumPath, []byte(""), 0644); err != nil {
-			return fmt.Errorf("failed to create go.sum for service '%s': %w", name, err)
+// goPackageName converts a service name into a valid Go package name ("order-items" -> "orderitems").
+func goPackageName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
 		}
-		fmt.Printf("Generated: %s\n", goSumPath)
 	}
+	return b.String()
+}
 
-	return nil
+// entityFilePath returns the shared entity file generated for a service of the given template kind.
+func entityFilePath(serviceName, template string) string {
+	if template == TemplateAuth {
+		return filepath.Join("pkg", "entities", "user.entity.go")
+	}
+	return filepath.Join("pkg", "entities", fmt.Sprintf("%s.entity.go", serviceName))
 }
 
-func createSharedPkg() error {
-	pkgPath := "pkg"
-	if _, err := os.Stat(pkgPath); os.IsNotExist(err) {
-		fmt.Println("Creating shared pkg/ folder...")
-		if err := os.Mkdir(pkgPath, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create pkg folder: %w", err)
-		}
-	} else {
-		fmt.Println("Shared pkg/ folder already exists, skipping creation.")
+// partialTemplates holds the framework-neutral {{define}} blocks, such as the request
+// validation of a controller, that the templates of every framework include.
+const partialTemplates = "templates/partials/"
+
+// renderTemplate executes an embedded template. Go output is run through go/format, so a
+// template that renders invalid Go fails here, naming the template, instead of in the
+// user's build.
+func renderTemplate(tmplPath, outputPath string, data interface{}) ([]byte, error) {
+	content, err := templatesFS.ReadFile(tmplPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", tmplPath, err)
 	}
 
-	// Create go.mod in pkg folder if not exists
-	pkgGoModPath := filepath.Join(pkgPath, "go.mod")
-	if _, err := os.Stat(pkgGoModPath); os.IsNotExist(err) {
-		fmt.Println("Creating pkg/go.mod...")
-		// NOTE: Customize the module path here if your monorepo root module is not 'gores'
-		pkgGoModContent := []byte("module gores/pkg\n\ngo 1.24\n")
-		if err := os.WriteFile(pkgGoModPath, pkgGoModContent, 0644); err != nil {
-			return fmt.Errorf("failed to create pkg/go.mod: %w", err)
-		}
-	} else {
-		fmt.Println("pkg/go.mod already exists, skipping creation.")
+	t, err := template.New(path.Base(tmplPath)).Funcs(templateFuncs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", tmplPath, err)
+	}
+	if err := parsePartials(t); err != nil {
+		return nil, err
 	}
 
-	// Create empty go.sum in pkg folder if not exists
-	pkgGoSumPath := filepath.Join(pkgPath, "go.sum")
-	if _, err := os.Stat(pkgGoSumPath); os.IsNotExist(err) {
-		fmt.Println("Creating pkg/go.sum...")
-		if err := os.WriteFile(pkgGoSumPath, []byte(""), 0644); err != nil {
-			return fmt.Errorf("failed to create pkg/go.sum: %w", err)
-		}
-	} else {
-		fmt.Println("pkg/go.sum already exists, skipping creation.")
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template %s into %s: %w", tmplPath, outputPath, err)
 	}
 
-	// Create pkg/entities folder
-	entitiesPkgPath := filepath.Join(pkgPath, "entities")
-	if _, err := os.Stat(entitiesPkgPath); os.IsNotExist(err) {
-		fmt.Println("Creating pkg/entities/ folder...")
-		if err := os.MkdirAll(entitiesPkgPath, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create pkg/entities folder: %w", err)
-		}
-	} else {
-		fmt.Println("pkg/entities/ folder already exists, skipping creation.")
+	if filepath.Ext(outputPath) != ".go" {
+		return buf.Bytes(), nil
 	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("template %s produced invalid Go in %s: %w", tmplPath, outputPath, err)
+	}
+	return formatted, nil
+}
 
-	// Create pkg/database folder and connection.go from embedded template
-	dbPkgPath := filepath.Join(pkgPath, "database")
-	if _, err := os.Stat(dbPkgPath); os.IsNotExist(err) {
-		fmt.Println("Creating pkg/database folder...")
-		if err := os.MkdirAll(dbPkgPath, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create pkg/database folder: %w", err)
+// parsePartials adds the blocks defined in partialTemplates to t. Each partial file is
+// parsed under its full path, so its name cannot clash with the including template.
+func parsePartials(t *template.Template) error {
+	partials, err := fs.Glob(templatesFS, partialTemplates+"*.tmpl")
+	if err != nil {
+		return fmt.Errorf("failed to list partial templates: %w", err)
+	}
+	for _, partial := range partials {
+		content, err := templatesFS.ReadFile(partial)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", partial, err)
+		}
+		if _, err := t.New(partial).Parse(string(content)); err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", partial, err)
 		}
-	} else {
-		fmt.Println("pkg/database folder already exists, skipping creation.")
 	}
+	return nil
+}
 
-	dbOutputPath := filepath.Join(dbPkgPath, "postgres", "connection.go") // Assume postgres subfolder
-	if err := os.MkdirAll(filepath.Dir(dbOutputPath), os.ModePerm); err != nil { // Create parent dirs
-		return fmt.Errorf("failed to create pkg/database/postgres folder: %w", err)
+// writeTemplate renders an embedded template into outputPath.
+func writeTemplate(fsys projectFS, tmplPath, outputPath string, data interface{}) error {
+	content, err := renderTemplate(tmplPath, outputPath, data)
+	if err != nil {
+		return err
 	}
+	if err := fsys.WriteFile(outputPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to create file %s: %w", outputPath, err)
+	}
+	return nil
+}
 
-	if _, err := os.Stat(dbOutputPath); os.IsNotExist(err) {
-		dbContent, err := templatesFS.ReadFile("templates/database_connection.tmpl")
-		if err != nil {
-			return fmt.Errorf("failed to read database connection template: %w", err)
-		}
+// renderServiceTemplates renders every template (template path -> output path) in a stable
+// order and records the result in files.
+func renderServiceTemplates(fsys projectFS, templates map[string]string, data TemplateData, files generatedFiles) error {
+	tmplPaths := make([]string, 0, len(templates))
+	for tmplPath := range templates {
+		tmplPaths = append(tmplPaths, tmplPath)
+	}
+	sort.Strings(tmplPaths)
 
-		dbTemplate, err := template.New("database_connection.tmpl").Parse(string(dbContent))
-		if err != nil {
-			return fmt.Errorf("failed to parse database connection template: %w", err)
+	for _, tmplPath := range tmplPaths {
+		outputPath := templates[tmplPath]
+		if err := writeTemplate(fsys, tmplPath, outputPath, data); err != nil {
+			return err
 		}
+		files[outputPath] = tmplPath
+		reportf(fsys, "Generated: %s\n", outputPath)
+	}
+	return nil
+}
 
-		dbFile, err := os.Create(dbOutputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %w", dbOutputPath, err)
+// writeEmptyGoSum creates an empty go.sum in a freshly generated service directory.
+func writeEmptyGoSum(fsys projectFS, name, serviceDirPath string) error {
+	goSumPath := filepath.Join(serviceDirPath, "go.sum")
+	if _, err := fsys.Stat(goSumPath); os.IsNotExist(err) {
+		if err := fsys.WriteFile(goSumPath, []byte(""), 0644); err != nil {
+			return fmt.Errorf("failed to create go.sum for service '%s': %w", name, err)
 		}
-		defer dbFile.Close()
+		reportf(fsys, "Generated: %s\n", goSumPath)
+	}
+	return nil
+}
 
-		err = dbTemplate.Execute(dbFile, nil) // no vars needed
-		if err != nil {
-			return fmt.Errorf("failed to execute database connection template: %w", err)
+func createAuthMicroservice(fsys projectFS, opts generatorOptions, name, port string) (generatedFiles, error) {
+	const templateRoot = "templates/auth/" // Hardcoded path for auth templates
+
+	serviceDirPath := filepath.Join(servicesDir, name)
+	internalDirPath := filepath.Join(serviceDirPath, "src/internal")
+	cmdDirPath := filepath.Join(serviceDirPath, "src/cmd")
+
+	foldersToCreate := []string{
+		serviceDirPath,
+		internalDirPath,
+		cmdDirPath,
+		filepath.Join("pkg", "entities"),
+	}
+
+	for _, folder := range foldersToCreate {
+		if err := fsys.MkdirAll(folder, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create folder %s: %w", folder, err)
 		}
-		fmt.Println("pkg/database/postgres/connection.go created.")
-	} else {
-		fmt.Println("pkg/database/postgres/connection.go already exists, skipping creation.")
 	}
 
+	data := newTemplateData(opts, name, port)
 
-	// Create pkg/http folder and middleware.go from embedded template
-	httpPkgPath := filepath.Join(pkgPath, "http")
-	if _, err := os.Stat(httpPkgPath); os.IsNotExist(err) {
-		fmt.Println("Creating pkg/http folder...")
-		if err := os.MkdirAll(httpPkgPath, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create pkg/http folder: %w", err)
-		}
-	} else {
-		fmt.Println("pkg/http folder already exists, skipping creation.")
+	// The HTTP layer comes from the auth templates of the project's framework.
+	templates := map[string]string{
+		frameworkTemplate(data.Framework, "auth/main.tmpl"):       filepath.Join(cmdDirPath, "main.go"),
+		frameworkTemplate(data.Framework, "auth/router.tmpl"):     filepath.Join(internalDirPath, "router.go"),
+		frameworkTemplate(data.Framework, "auth/controller.tmpl"): filepath.Join(internalDirPath, "controller.go"),
+		templateRoot + "service.tmpl":                             filepath.Join(internalDirPath, "service.go"),
+		templateRoot + "go.mod.tmpl":                              filepath.Join(serviceDirPath, "go.mod"),
+		templateRoot + "Dockerfile.tmpl":                          filepath.Join(serviceDirPath, "Dockerfile"),
+		templateRoot + "entity.tmpl":                              entityFilePath(name, TemplateAuth),
 	}
 
-	middlewareOutputPath := filepath.Join(httpPkgPath, "middleware", "middleware.go") // Assume middleware subfolder
-	if err := os.MkdirAll(filepath.Dir(middlewareOutputPath), os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create pkg/http/middleware folder: %w", err)
+	files := generatedFiles{}
+	if err := renderServiceTemplates(fsys, templates, data, files); err != nil {
+		return files, err
 	}
+	return files, writeEmptyGoSum(fsys, name, serviceDirPath)
+}
 
-	if _, err := os.Stat(middlewareOutputPath); os.IsNotExist(err) {
-		middlewareContent, err := templatesFS.ReadFile("templates/middleware.tmpl")
-		if err != nil {
-			return fmt.Errorf("failed to read middleware template: %w", err)
+// createSharedPkg creates the shared pkg module, leaving existing files untouched, and
+// returns the templated files it wrote.
+func createSharedPkg(fsys projectFS, opts generatorOptions) (generatedFiles, error) {
+	pkgPath := "pkg"
+	if _, err := fsys.Stat(pkgPath); os.IsNotExist(err) {
+		reportf(fsys, "Creating shared pkg/ folder...\n")
+		if err := fsys.MkdirAll(pkgPath, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create pkg folder: %w", err)
 		}
+	} else {
+		reportf(fsys, "Shared pkg/ folder already exists, skipping creation.\n")
+	}
 
-		middlewareTemplate, err := template.New("middleware.tmpl").Parse(string(middlewareContent))
-		if err != nil {
-			return fmt.Errorf("failed to parse middleware template: %w", err)
+	// Create pkg/entities folder
+	entitiesPkgPath := filepath.Join(pkgPath, "entities")
+	if _, err := fsys.Stat(entitiesPkgPath); os.IsNotExist(err) {
+		reportf(fsys, "Creating pkg/entities/ folder...\n")
+		if err := fsys.MkdirAll(entitiesPkgPath, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create pkg/entities folder: %w", err)
 		}
+	} else {
+		reportf(fsys, "pkg/entities/ folder already exists, skipping creation.\n")
+	}
 
-		middlewareFile, err := os.Create(middlewareOutputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %w", middlewareOutputPath, err)
+	// Render go.mod, the database connection and the HTTP middleware from embedded templates.
+	data := newTemplateData(opts, "", "")
+	data.Databases = []string{data.Database}
+	files := generatedFiles{}
+	for _, f := range pkgTemplates(data.Framework, data.Databases) {
+		display := filepath.ToSlash(f.output)
+		if _, err := fsys.Stat(f.output); err == nil {
+			reportf(fsys, "%s already exists, skipping creation.\n", display)
+			continue
 		}
-		defer middlewareFile.Close()
+		if err := fsys.MkdirAll(filepath.Dir(f.output), os.ModePerm); err != nil {
+			return files, fmt.Errorf("failed to create %s folder: %w", filepath.ToSlash(filepath.Dir(f.output)), err)
+		}
+		if err := writeTemplate(fsys, f.template, f.output, data); err != nil {
+			return files, err
+		}
+		files[f.output] = f.template
+		reportf(fsys, "%s created.\n", display)
+	}
 
-		err = middlewareTemplate.Execute(middlewareFile, nil) // no vars
-		if err != nil {
-			return fmt.Errorf("failed to execute middleware template: %w", err)
+	// Create empty go.sum in pkg folder if not exists
+	pkgGoSumPath := filepath.Join(pkgPath, "go.sum")
+	if _, err := fsys.Stat(pkgGoSumPath); os.IsNotExist(err) {
+		reportf(fsys, "Creating pkg/go.sum...\n")
+		if err := fsys.WriteFile(pkgGoSumPath, []byte(""), 0644); err != nil {
+			return files, fmt.Errorf("failed to create pkg/go.sum: %w", err)
 		}
-		fmt.Println("pkg/http/middleware.go created.")
 	} else {
-		fmt.Println("pkg/http/middleware.go already exists, skipping creation.")
+		reportf(fsys, "pkg/go.sum already exists, skipping creation.\n")
 	}
 
-	return nil
+	return files, nil
 }
 
-func createMicroservice(name, port, templateRoot string) error {
-	serviceDirPath := filepath.Join("services", name)
+func createMicroservice(fsys projectFS, opts generatorOptions, name, port, templateRoot string, entities []EntitySpec) (generatedFiles, error) {
+	serviceDirPath := filepath.Join(servicesDir, name)
 	internalDirPath := filepath.Join(serviceDirPath, "internal")
 	cmdDirPath := filepath.Join(serviceDirPath, "cmd")
 
@@ -243,81 +442,169 @@ func createMicroservice(name, port, templateRoot string) error {
 		serviceDirPath,
 		internalDirPath,
 		cmdDirPath,
+		filepath.Join("pkg", "entities"),
+	}
+	if opts.API == APIGRPC {
+		foldersToCreate = append(foldersToCreate, filepath.Join(serviceDirPath, "proto"))
 	}
 
 	for _, folder := range foldersToCreate {
-		if err := os.MkdirAll(folder, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create folder %s: %w", folder, err)
+		if err := fsys.MkdirAll(folder, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create folder %s: %w", folder, err)
 		}
 	}
 
-	templates := map[string]string{
-		"main.tmpl":       filepath.Join(cmdDirPath, "main.go"),
-		"router.tmpl":     filepath.Join(internalDirPath, "router.go"),
-		"controller.tmpl": filepath.Join(internalDirPath, "controller.go"),
-		"service.tmpl":    filepath.Join(internalDirPath, "service.go"),
-		"go.mod.tmpl":     filepath.Join(serviceDirPath, "go.mod"),
-		"Dockerfile.tmpl": filepath.Join(serviceDirPath, "Dockerfile"),
-		// entities are shared, so they always come from the base 'templates/'
-		"entity_pkg.tmpl": filepath.Join("pkg", "entities", fmt.Sprintf("%s.entity.go", name)),
+	if len(entities) == 0 {
+		entities = []EntitySpec{newEntitySpec(name, nil)}
 	}
+	data := newTemplateData(opts, name, port)
+	data.Entities = entities
 
-	cwd, err := os.Getwd()
+	// A service on a database the project has not used yet brings its connection package.
+	files, err := ensureDatabasePkg(fsys, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %w", err)
+		return files, err
 	}
-	rootDir := filepath.Base(cwd) // This assumes 'gores' is the current working directory base name.
-
-	data := TemplateData{
-		Name:    name,
-		Port:    port,
-		RootDir: rootDir,
+	// The HTTP layer (main, routers, controllers and their tests) comes from the templates
+	// of the project's framework. gRPC services replace it with their .proto file, the
+	// auth interceptors and a server per entity that protoc's generated code calls into.
+	// GraphQL services keep the framework's main but serve every entity through one
+	// schema, with a resolver per entity instead of its routes and controller.
+	// HTTP services also describe their routes in an OpenAPI spec, which they serve, and
+	// get a typed client in the shared module for other services to call them with.
+	templates := map[string]string{
+		templateRoot + "go.mod.tmpl":     filepath.Join(serviceDirPath, "go.mod"),
+		templateRoot + "Dockerfile.tmpl": filepath.Join(serviceDirPath, "Dockerfile"),
+	}
+	var apiTemplates map[string]string // Templates of every exposed entity, to their file names
+	if data.API == APIGRPC {
+		protoDirPath := filepath.Join(serviceDirPath, "proto")
+		templates[grpcTemplates+"main.tmpl"] = filepath.Join(cmdDirPath, "main.go")
+		templates[grpcTemplates+"proto.tmpl"] = filepath.Join(protoDirPath, name+".proto")
+		templates[grpcTemplates+"generate.tmpl"] = filepath.Join(protoDirPath, "generate.go")
+		templates[grpcTemplates+"tools.tmpl"] = filepath.Join(protoDirPath, "tools.go")
+		templates[grpcTemplates+"interceptors.tmpl"] = filepath.Join(internalDirPath, "interceptors.go")
+		templates[grpcTemplates+"interceptors_test.tmpl"] = filepath.Join(internalDirPath, "interceptors_test.go")
+		templates[grpcTemplates+"helpers_test.tmpl"] = filepath.Join(internalDirPath, "helpers_test.go")
+		apiTemplates = map[string]string{
+			grpcTemplates + "server.tmpl":      "grpc_server.go",
+			grpcTemplates + "server_test.tmpl": "grpc_server_test.go",
+		}
+	} else {
+		templates[frameworkTemplate(data.Framework, "main.tmpl")] = filepath.Join(cmdDirPath, "main.go")
+		templates[frameworkTemplate(data.Framework, "helpers_test.tmpl")] = filepath.Join(internalDirPath, "helpers_test.go")
+		apiTemplates = map[string]string{
+			frameworkTemplate(data.Framework, "router.tmpl"):          "router.go",
+			frameworkTemplate(data.Framework, "controller.tmpl"):      "controller.go",
+			frameworkTemplate(data.Framework, "controller_test.tmpl"): "controller_test.go",
+		}
+	}
+	if data.API == APIGraphQL {
+		templates[graphqlTemplates+"schema.tmpl"] = filepath.Join(internalDirPath, "schema.graphql")
+		templates[graphqlTemplates+"graphql.tmpl"] = filepath.Join(internalDirPath, "graphql.go")
+		templates[frameworkTemplate(data.Framework, "graphql_router.tmpl")] = filepath.Join(internalDirPath, "graphql_router.go")
+		templates[graphqlTemplates+"router_test.tmpl"] = filepath.Join(internalDirPath, "graphql_router_test.go")
+		apiTemplates = map[string]string{
+			graphqlTemplates + "resolver.tmpl":      "resolver.go",
+			graphqlTemplates + "resolver_test.tmpl": "resolver_test.go",
+		}
+	}
+	if data.API == APIHTTP {
+		templates[templateRoot+"openapi.yaml.tmpl"] = filepath.Join(internalDirPath, "openapi.yaml")
+		templates[templateRoot+"openapi.tmpl"] = filepath.Join(internalDirPath, "openapi.go")
+		templates[frameworkTemplate(data.Framework, "openapi_router.tmpl")] = filepath.Join(internalDirPath, "openapi_router.go")
+		templates[templateRoot+"openapi_test.tmpl"] = filepath.Join(internalDirPath, "openapi_test.go")
+		if len(data.ExposedEntities()) > 0 {
+			if err := addClientTemplates(fsys, opts, name, templates, files); err != nil {
+				return files, err
+			}
+		}
+	}
+	if err := renderServiceTemplates(fsys, templates, data, files); err != nil {
+		return files, err
 	}
 
-	funcMap := template.FuncMap{
-		"lower": strings.ToLower,
-		"title": strings.Title,
+	// Every service gets the in-memory repository, which backs its unit tests and is the
+	// only storage of services without a database; MongoDB services use the official
+	// driver instead of GORM.
+	repository := "repository_gorm" // Template and file name of the database's repository
+	switch data.Database {
+	case DatabaseNone:
+		repository = ""
+	case DatabaseMongo:
+		repository = "repository_mongo"
 	}
 
-	for tmplFile, outputPath := range templates {
-		// For entity_pkg.tmpl, always use the base "templates/" path
-		// as entities are generic and not service-specific template variations.
-		actualTemplatePath := templateRoot + tmplFile
-		if tmplFile == "entity_pkg.tmpl" {
-			actualTemplatePath = "templates/" + tmplFile
+	// The entity named after the service keeps the plain file names; other entities
+	// get theirs prefixed, e.g. internal/line_items_controller.go.
+	for _, entity := range entities {
+		prefix := ""
+		if entity.Name != name {
+			prefix = snakeCase(entity.Name) + "_"
 		}
-
-		content, err := templatesFS.ReadFile(actualTemplatePath)
-		if err != nil {
-			return fmt.Errorf("failed to read template %s from %s: %w", tmplFile, actualTemplatePath, err)
+		templates := map[string]string{
+			templateRoot + "service.tmpl":           filepath.Join(internalDirPath, prefix+"service.go"),
+			templateRoot + "service_test.tmpl":      filepath.Join(internalDirPath, prefix+"service_test.go"),
+			templateRoot + "repository.tmpl":        filepath.Join(internalDirPath, prefix+"repository.go"),
+			templateRoot + "repository_memory.tmpl": filepath.Join(internalDirPath, prefix+"repository_memory.go"),
+			// entities are shared, so they always come from the base 'templates/'
+			"templates/entity_pkg.tmpl": entityFilePath(entity.Name, TemplateGeneric),
 		}
-
-		t, err := template.New(tmplFile).Funcs(funcMap).Parse(string(content))
-		if err != nil {
-			return fmt.Errorf("failed to parse template %s: %w", tmplFile, err)
+		if repository != "" {
+			templates[templateRoot+repository+".tmpl"] = filepath.Join(internalDirPath, prefix+repository+".go")
 		}
-
-		f, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %w", outputPath, err)
+		if entity.Expose {
+			for tmplPath, file := range apiTemplates {
+				templates[tmplPath] = filepath.Join(internalDirPath, prefix+file)
+			}
 		}
-
-		err = t.Execute(f, data)
-		f.Close() // Close the file immediately after execution
-		if err != nil {
-			return fmt.Errorf("failed to execute template %s into %s: %w", tmplFile, outputPath, err)
+		entityData := data
+		entityData.Entity = entity
+		if err := renderServiceTemplates(fsys, templates, entityData, files); err != nil {
+			return files, err
 		}
-		fmt.Printf("Generated: %s\n", outputPath)
 	}
 
-	// Create empty go.sum file for the new service
-	goSumPath := filepath.Join(serviceDirPath, "go.sum")
-	if _, err := os.Stat(goSumPath); os.IsNotExist(err) {
-		if err := os.WriteFile(goSumPath, []byte(""), 0644); err != nil {
-			return fmt.Errorf("failed to create go.sum for service '%s': %w", name, err)
+	// The entities live in the shared module, which needs the packages their field types come from.
+	if data.HasFieldKind(FieldDecimal) {
+		if err := requirePkgDependency(fsys, "github.com/shopspring/decimal", "v1.4.0"); err != nil {
+			return files, err
 		}
-		fmt.Printf("Generated: %s\n", goSumPath)
 	}
+	return files, writeEmptyGoSum(fsys, name, serviceDirPath)
+}
 
+// requirePkgDependency adds a requirement to pkg/go.mod unless the module is already
+// required. A missing pkg/go.mod is left alone.
+func requirePkgDependency(fsys projectFS, path, version string) error {
+	goModPath := filepath.Join("pkg", "go.mod")
+	content, err := fsys.ReadFile(goModPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", goModPath, err)
+	}
+	modFile, err := modfile.Parse(goModPath, content, nil)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", goModPath, err)
+	}
+	for _, r := range modFile.Require {
+		if r.Mod.Path == path {
+			return nil
+		}
+	}
+	if err := modFile.AddRequire(path, version); err != nil {
+		return fmt.Errorf("failed to add %s to %s: %w", path, goModPath, err)
+	}
+	modFile.SortBlocks()
+	modFile.Cleanup()
+	out, err := modFile.Format()
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", goModPath, err)
+	}
+	if err := fsys.WriteFile(goModPath, out, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", goModPath, err)
+	}
+	reportf(fsys, "Updated: %s (requires %s)\n", filepath.ToSlash(goModPath), path)
 	return nil
-}
\ No newline at end of file
+}
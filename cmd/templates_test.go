@@ -0,0 +1,522 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenServiceNames covers the service name shapes the generic templates must handle:
+// hyphenated, camelCase, uppercase, snake_case and Go reserved words.
+var goldenServiceNames = []string{"order-items", "orderItems", "ORDERS", "order_items", "type", "func"}
+
+// unwiredTemplates are embedded snippets that no generator renders yet.
+const unwiredTemplates = "templates/config/"
+
+type goldenCase struct {
+	name     string
+	generate func() (generatedFiles, error)
+}
+
+func goldenCases() []goldenCase {
+	cases := []goldenCase{
+		{"pkg", func() (generatedFiles, error) {
+			return createSharedPkg(osFS{}, generatorOptions{Module: defaultModulePath})
+		}},
+		{"auth", func() (generatedFiles, error) {
+			return createAuthMicroservice(osFS{}, generatorOptions{Module: defaultModulePath}, authServiceName, "8080")
+		}},
+	}
+	for _, name := range goldenServiceNames {
+		name := name
+		cases = append(cases, goldenCase{"generic/" + name, func() (generatedFiles, error) {
+			return createMicroservice(osFS{}, generatorOptions{Module: defaultModulePath}, name, "8081", "templates/", nil)
+		}})
+	}
+	cases = append(cases, goldenCase{"fields", func() (generatedFiles, error) {
+		fields, err := parseFieldSpecs("Orders", goldenFieldSpecs)
+		if err != nil {
+			return nil, err
+		}
+		return createMicroservice(osFS{}, generatorOptions{Module: defaultModulePath}, "orders", "8081", "templates/",
+			[]EntitySpec{newEntitySpec("orders", fields)})
+	}})
+	cases = append(cases, goldenCase{"schema", func() (generatedFiles, error) {
+		schema, entities, err := loadSchemaFile(goldenSchemaPath, "")
+		if err != nil {
+			return nil, err
+		}
+		return createMicroservice(osFS{}, generatorOptions{Module: defaultModulePath}, schema.Service, "8081", "templates/", entities)
+	}})
+	// A SQLite project from init on, a MySQL service added to a PostgreSQL project, and a
+	// service without a database.
+	cases = append(cases, goldenCase{"database/sqlite", func() (generatedFiles, error) {
+		opts := generatorOptions{Module: defaultModulePath, Database: DatabaseSQLite}
+		return generateAll(
+			func() (generatedFiles, error) { return createSharedPkg(osFS{}, opts) },
+			func() (generatedFiles, error) { return createAuthMicroservice(osFS{}, opts, authServiceName, "8080") },
+			func() (generatedFiles, error) { return createFieldsService(opts) },
+		)
+	}})
+	cases = append(cases, goldenCase{"database/mysql", func() (generatedFiles, error) {
+		return generateAll(
+			func() (generatedFiles, error) {
+				return createSharedPkg(osFS{}, generatorOptions{Module: defaultModulePath})
+			},
+			func() (generatedFiles, error) {
+				return createFieldsService(generatorOptions{Module: defaultModulePath, Database: DatabaseMySQL})
+			},
+		)
+	}})
+	cases = append(cases, goldenCase{"database/none", func() (generatedFiles, error) {
+		return createFieldsService(generatorOptions{Module: defaultModulePath, Database: DatabaseNone})
+	}})
+	// MongoDB services added to a PostgreSQL project, with either ID strategy.
+	cases = append(cases, goldenCase{"database/mongo", func() (generatedFiles, error) {
+		return generateAll(
+			func() (generatedFiles, error) {
+				return createSharedPkg(osFS{}, generatorOptions{Module: defaultModulePath})
+			},
+			func() (generatedFiles, error) {
+				return createFieldsService(generatorOptions{Module: defaultModulePath, Database: DatabaseMongo})
+			},
+		)
+	}})
+	cases = append(cases, goldenCase{"database/mongo-uuid", func() (generatedFiles, error) {
+		return createFieldsService(generatorOptions{Module: defaultModulePath, Database: DatabaseMongo, IDStrategy: IDStrategyUUID})
+	}})
+	// A project on every other framework, with the schema service for relations and
+	// partial APIs and a service with every field kind.
+	for _, framework := range frameworkNames[1:] {
+		opts := generatorOptions{Module: defaultModulePath, Framework: framework}
+		cases = append(cases, goldenCase{"framework/" + strings.ReplaceAll(framework, "/", "-"), func() (generatedFiles, error) {
+			return generateAll(
+				func() (generatedFiles, error) { return createSharedPkg(osFS{}, opts) },
+				func() (generatedFiles, error) { return createAuthMicroservice(osFS{}, opts, authServiceName, "8080") },
+				func() (generatedFiles, error) {
+					schema, entities, err := loadSchemaFile(goldenSchemaPath, "")
+					if err != nil {
+						return nil, err
+					}
+					return createMicroservice(osFS{}, opts, schema.Service, "8081", "templates/", entities)
+				},
+				func() (generatedFiles, error) {
+					fields, err := parseFieldSpecs("Payments", goldenFieldSpecs)
+					if err != nil {
+						return nil, err
+					}
+					return createMicroservice(osFS{}, opts, "payments", "8082", "templates/", []EntitySpec{newEntitySpec("payments", fields)})
+				},
+			)
+		}})
+	}
+	// gRPC services: the schema service for several entities and partial APIs, and a
+	// service with every field kind on in-memory storage.
+	cases = append(cases, goldenCase{"api/grpc", func() (generatedFiles, error) {
+		opts := generatorOptions{Module: defaultModulePath, API: APIGRPC}
+		return generateAll(
+			func() (generatedFiles, error) {
+				schema, entities, err := loadSchemaFile(goldenSchemaPath, "")
+				if err != nil {
+					return nil, err
+				}
+				return createMicroservice(osFS{}, opts, schema.Service, "8081", "templates/", entities)
+			},
+			func() (generatedFiles, error) {
+				fields, err := parseFieldSpecs("Payments", goldenFieldSpecs)
+				if err != nil {
+					return nil, err
+				}
+				noDB := opts
+				noDB.Database = DatabaseNone
+				return createMicroservice(osFS{}, noDB, "payments", "8082", "templates/", []EntitySpec{newEntitySpec("payments", fields)})
+			},
+		)
+	}})
+	// GraphQL services: the schema service on Fiber, a service with every field kind on
+	// net/http, and a service without fields on each other framework.
+	cases = append(cases, goldenCase{"api/graphql", func() (generatedFiles, error) {
+		opts := generatorOptions{Module: defaultModulePath, API: APIGraphQL}
+		generators := []func() (generatedFiles, error){
+			func() (generatedFiles, error) {
+				schema, entities, err := loadSchemaFile(goldenSchemaPath, "")
+				if err != nil {
+					return nil, err
+				}
+				return createMicroservice(osFS{}, opts, schema.Service, "8081", "templates/", entities)
+			},
+			func() (generatedFiles, error) {
+				fields, err := parseFieldSpecs("Payments", goldenFieldSpecs)
+				if err != nil {
+					return nil, err
+				}
+				netHTTP := opts
+				netHTTP.Framework, netHTTP.Database = FrameworkNetHTTP, DatabaseNone
+				return createMicroservice(osFS{}, netHTTP, "payments", "8082", "templates/", []EntitySpec{newEntitySpec("payments", fields)})
+			},
+		}
+		for _, service := range []struct{ framework, name, port string }{
+			{FrameworkChi, "reviews", "8083"}, {FrameworkGin, "ratings", "8084"}, {FrameworkEcho, "comments", "8085"},
+		} {
+			service := service
+			generators = append(generators, func() (generatedFiles, error) {
+				framed := opts
+				framed.Framework, framed.Database = service.framework, DatabaseNone
+				return createMicroservice(osFS{}, framed, service.name, service.port, "templates/", nil)
+			})
+		}
+		return generateAll(generators...)
+	}})
+	cases = append(cases, goldenCase{"migration", func() (generatedFiles, error) {
+		_, entities, err := loadSchemaFile(goldenSchemaPath, "")
+		if err != nil {
+			return nil, err
+		}
+		files, err := createMigration(osFS{}, "orders", "20240102150405", "create_orders", entities)
+		if err != nil {
+			return nil, err
+		}
+		more, err := createMigration(osFS{}, "orders", "20240103090000", "add_note", nil)
+		if err != nil {
+			return nil, err
+		}
+		for file, tmpl := range more {
+			files[file] = tmpl
+		}
+		// A migration written by 'gores migration diff'.
+		more, err = writeMigration(osFS{}, migrationData{
+			Service: "orders", Version: "20240104090000", Name: "update_orders",
+			Tables: []sqlTable{{Name: "refunds", Columns: []string{`"id" uuid PRIMARY KEY DEFAULT gen_random_uuid()`, `"order_id" uuid NOT NULL REFERENCES "orders" ("id")`}}},
+			Up:     []string{`ALTER TABLE "orders" ADD COLUMN "note" text`, `CREATE INDEX "idx_orders_note" ON "orders" ("note")`},
+			Down:   []string{`DROP INDEX IF EXISTS "idx_orders_note"`, `ALTER TABLE "orders" DROP COLUMN "note"`},
+		})
+		for file, tmpl := range more {
+			files[file] = tmpl
+		}
+		return files, err
+	}})
+	return cases
+}
+
+// createFieldsService generates the orders service with every field kind.
+func createFieldsService(opts generatorOptions) (generatedFiles, error) {
+	fields, err := parseFieldSpecs("Orders", goldenFieldSpecs)
+	if err != nil {
+		return nil, err
+	}
+	return createMicroservice(osFS{}, opts, "orders", "8081", "templates/", []EntitySpec{newEntitySpec("orders", fields)})
+}
+
+// generateAll runs several generators into one tree and merges the files they report.
+func generateAll(generators ...func() (generatedFiles, error)) (generatedFiles, error) {
+	files := generatedFiles{}
+	for _, generate := range generators {
+		more, err := generate()
+		for file, tmpl := range more {
+			files[file] = tmpl
+		}
+		if err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// goldenSchemaPath is resolved before any test changes the working directory.
+var goldenSchemaPath, _ = filepath.Abs(filepath.Join("testdata", "schema", "orders.yaml"))
+
+// goldenFieldSpecs exercises every field kind and modifier.
+var goldenFieldSpecs = []string{
+	"customerEmail:string:required:unique",
+	"note:string:optional",
+	"quantity:int:required",
+	"paid:bool",
+	"paidAt:time:nullable",
+	"customerId:uuid:required:indexed",
+	"couponId:uuid:optional",
+	"total:decimal:required",
+	"discount:decimal:optional",
+	"metadata:json",
+	"status:enum(pending,paid,shipped):required:index",
+	"channel:enum(web,in-store):optional",
+}
+
+// chdir switches into dir for the rest of the test. The generators write relative to the
+// working directory, so tests using it must not run in parallel.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("failed to restore working directory: %v", err)
+		}
+	})
+}
+
+func TestTemplatesGolden(t *testing.T) {
+	goldenRoot, err := filepath.Abs(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range goldenCases() {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			out := t.TempDir()
+			chdir(t, out)
+			if _, err := tc.generate(); err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+
+			got := readTree(t, out)
+			goldenDir := filepath.Join(goldenRoot, filepath.FromSlash(tc.name))
+			for rel, content := range got {
+				if strings.HasSuffix(rel, ".go") {
+					if _, err := parser.ParseFile(token.NewFileSet(), rel, content, parser.AllErrors); err != nil {
+						t.Errorf("%s does not parse: %v", rel, err)
+					}
+				}
+				if filepath.Base(rel) == "openapi.yaml" {
+					if err := checkOpenAPISpec(content); err != nil {
+						t.Errorf("%s: %v", rel, err)
+					}
+				}
+			}
+
+			if *update {
+				if err := os.RemoveAll(goldenDir); err != nil {
+					t.Fatal(err)
+				}
+				for rel, content := range got {
+					path := filepath.Join(goldenDir, filepath.FromSlash(rel)+".golden")
+					if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, content, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return
+			}
+
+			want := readTree(t, goldenDir)
+			for rel, content := range got {
+				golden, ok := want[rel+".golden"]
+				if !ok {
+					t.Errorf("%s has no golden file; run 'go test ./cmd -run TestTemplatesGolden -update'", rel)
+					continue
+				}
+				if !bytes.Equal(content, golden) {
+					t.Errorf("%s differs from its golden file:\n%s", rel, firstDiff(golden, content))
+				}
+			}
+			for rel := range want {
+				if _, ok := got[strings.TrimSuffix(rel, ".golden")]; !ok {
+					t.Errorf("golden file %s is no longer generated", rel)
+				}
+			}
+		})
+	}
+}
+
+// checkOpenAPISpec checks that a generated OpenAPI spec converts to JSON, as the service
+// serves it, and that each of its references points at a declared component.
+func checkOpenAPISpec(content []byte) error {
+	var spec any
+	if err := yaml.Unmarshal(content, &spec); err != nil {
+		return err
+	}
+	if _, err := json.Marshal(spec); err != nil {
+		return fmt.Errorf("does not convert to JSON: %w", err)
+	}
+	var check func(node any) error
+	check = func(node any) error {
+		switch node := node.(type) {
+		case map[string]any:
+			if ref, ok := node["$ref"].(string); ok {
+				var target any = spec
+				for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					m, _ := target.(map[string]any)
+					if target, ok = m[key]; !ok {
+						return fmt.Errorf("unresolved reference %s", ref)
+					}
+				}
+			}
+			for _, v := range node {
+				if err := check(v); err != nil {
+					return err
+				}
+			}
+		case []any:
+			for _, v := range node {
+				if err := check(v); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return check(spec)
+}
+
+// TestEveryTemplateIsCovered fails when a template is embedded but not exercised by the
+// golden cases, so new templates cannot slip in untested.
+func TestEveryTemplateIsCovered(t *testing.T) {
+	used := map[string]bool{}
+	for _, tc := range goldenCases() {
+		// Every case starts from an empty project, as generators skip the files that exist.
+		chdir(t, t.TempDir())
+		files, err := tc.generate()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for _, tmpl := range files {
+			used[tmpl] = true
+		}
+	}
+
+	err := fs.WalkDir(templatesFS, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasPrefix(path, partialTemplates) {
+			return nil // Included by other templates, which the golden cases render.
+		}
+		if strings.HasPrefix(path, unwiredTemplates) {
+			// Snippets are not rendered on their own, but must still be valid templates.
+			_, err := renderTemplate(path, filepath.Base(path), newTemplateData(generatorOptions{Module: defaultModulePath}, "orders", "8081"))
+			if err != nil {
+				t.Errorf("%s: %v", path, err)
+			}
+			return nil
+		}
+		if !used[path] {
+			t.Errorf("%s is not covered by any golden case", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTree returns the contents of every file below root, keyed by slash-separated relative path.
+func readTree(t *testing.T, root string) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// firstDiff describes the first line at which got departs from want.
+func firstDiff(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return "(trailing whitespace only)"
+}
+
+func TestModulePathIsThreadedThroughTemplates(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	mem := newMemFS()
+	if _, err := createMicroservice(mem, generatorOptions{Module: "github.com/acme/platform"}, "orders", "8081", "templates/", nil); err != nil {
+		t.Fatalf("createMicroservice: %v", err)
+	}
+	for file, want := range map[string]string{
+		filepath.Join(servicesDir, "orders", "go.mod"):                "module github.com/acme/platform/services/orders\n",
+		filepath.Join(servicesDir, "orders", "cmd", "main.go"):        `"github.com/acme/platform/services/orders/internal"`,
+		filepath.Join(servicesDir, "orders", "internal", "router.go"): `"github.com/acme/platform/pkg/http/middleware"`,
+	} {
+		content, err := mem.ReadFile(file)
+		if err != nil || !strings.Contains(string(content), want) {
+			t.Errorf("%s does not contain %s (err: %v)", file, want, err)
+		}
+	}
+}
+
+func TestReplaceDirectivesOption(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	for _, replace := range []bool{false, true} {
+		mem := newMemFS()
+		opts := generatorOptions{Module: defaultModulePath, Replace: replace}
+		if _, err := createMicroservice(mem, opts, "orders", "8081", "templates/", nil); err != nil {
+			t.Fatalf("createMicroservice: %v", err)
+		}
+		goMod, _ := mem.ReadFile(filepath.Join(servicesDir, "orders", "go.mod"))
+		dockerfile, _ := mem.ReadFile(filepath.Join(servicesDir, "orders", "Dockerfile"))
+
+		hasReplace := strings.Contains(string(goMod), "replace gores/pkg => ../../pkg\n") &&
+			strings.Contains(string(goMod), "\tgores/pkg v0.0.0\n")
+		hasWorkInit := strings.Contains(string(dockerfile), "go work init ./pkg ./services/orders")
+		if hasReplace != replace || hasWorkInit == replace {
+			t.Errorf("Replace=%v: go.mod:\n%s\nDockerfile:\n%s", replace, goMod, dockerfile)
+		}
+	}
+}
+
+func TestSyncWorkspace(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	if changed, err := syncWorkspace(osFS{}, []string{serviceModuleDir("orders")}, false); err != nil || changed {
+		t.Fatalf("syncWorkspace without go.work: changed=%v, err=%v", changed, err)
+	}
+	if _, err := syncWorkspace(osFS{}, []string{pkgModuleDir, serviceModuleDir("orders")}, true); err != nil {
+		t.Fatalf("syncWorkspace: %v", err)
+	}
+	if changed, err := syncWorkspace(osFS{}, []string{serviceModuleDir("orders"), serviceModuleDir("billing")}, false); err != nil || !changed {
+		t.Fatalf("syncWorkspace: changed=%v, err=%v", changed, err)
+	}
+	content, _ := os.ReadFile(goWorkFile)
+	want := "go 1.24\n\nuse (\n\t./pkg\n\t./services/billing\n\t./services/orders\n)\n"
+	if string(content) != want {
+		t.Fatalf("go.work:\n%s\nwant:\n%s", content, want)
+	}
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// pkgTarget names the shared pkg module as an upgrade target.
+const pkgTarget = "pkg"
+
+var upgradeDryRun bool
+
+// Outcomes of upgrading a single generated file.
+const (
+	upgradeUnchanged = "unchanged"
+	upgradeUpdated   = "updated"
+	upgradeMerged    = "merged"
+	upgradeConflict  = "conflict"
+	upgradeAdded     = "added"
+	upgradeSkipped   = "skipped"
+)
+
+// upgradeCmd is the Cobra command for re-applying the current templates to existing services.
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [service-name...]",
+	Short: "Re-apply the current templates to existing services",
+	Long: "Renders the templates of this gores version for the given services (default: the shared pkg module and every " +
+		"service in gores.yaml) and three-way merges them with your edits, using the pristine renderings recorded in " +
+		".gores/ as the common base. Hunks changed on both sides are left with conflict markers. Name 'pkg' to upgrade " +
+		"the shared module only.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --- Prerequisite Check ---
+		manifest, err := loadProjectManifest()
+		if err != nil {
+			return err
+		}
+		// --- End Prerequisite Check ---
+
+		targets := args
+		if len(targets) == 0 {
+			targets = []string{pkgTarget}
+			for _, s := range manifest.Services {
+				targets = append(targets, s.Name)
+			}
+		}
+		for _, target := range targets {
+			if target != pkgTarget && manifest.Service(target) == nil {
+				return fmt.Errorf("service '%s' is not registered in %s", target, ManifestFile)
+			}
+		}
+
+		idx, err := loadBaselineIndex()
+		if err != nil {
+			return err
+		}
+		if len(idx.Files) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: no baselines found in %s; only files that still match the templates exactly can be upgraded.\n", goresDir)
+		}
+
+		var results []upgradeResult
+		for _, target := range targets {
+			rendered, err := renderUpgradeTarget(manifest, target)
+			if err != nil {
+				return err
+			}
+			baseVersion := "baseline"
+			if s := manifest.Service(target); s != nil && s.GoresVersion != "" {
+				baseVersion = "gores " + s.GoresVersion
+			}
+			labels := mergeLabels{ours: "yours", base: baseVersion, theirs: "gores " + Version}
+			for _, f := range rendered {
+				results = append(results, upgradeFile(idx, f, labels))
+			}
+		}
+
+		if upgradeDryRun {
+			printUpgradeSummary("Upgrade", results, true)
+			return nil
+		}
+
+		if err := applyUpgradeResults(results); err != nil {
+			return err
+		}
+
+		_, err = UpdateManifest(ManifestFile, func(m *Manifest) error {
+			for _, target := range targets {
+				if s := m.Service(target); s != nil {
+					s.GoresVersion = Version
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("files were upgraded but updating %s failed: %w", ManifestFile, err)
+		}
+
+		if conflicts := printUpgradeSummary("Upgrade", results, false); conflicts > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("upgrade left conflicts in %d file(s); resolve the conflict markers and rebuild", conflicts)
+		}
+		return nil
+	},
+}
+
+// renderedFile is the output of a template as rendered by this gores version.
+type renderedFile struct {
+	path     string
+	template string
+	content  []byte
+}
+
+// upgradeResult records what upgrading one file did or would do.
+type upgradeResult struct {
+	file      string
+	template  string
+	outcome   string
+	reason    string
+	conflicts int
+
+	write          []byte // New content for the file, nil to leave it alone
+	theirs         []byte // Current rendering of the template
+	recordBaseline bool   // Whether theirs becomes the file's new baseline
+}
+
+// renderUpgradeTarget renders every templated file of target into memory.
+func renderUpgradeTarget(manifest *Manifest, target string) ([]renderedFile, error) {
+	if target == pkgTarget {
+		var rendered []renderedFile
+		data := newTemplateData(projectGeneratorOptions(manifest), "", "")
+		data.Databases = manifest.Databases()
+		for _, f := range pkgTemplates(data.Framework, data.Databases) {
+			content, err := renderTemplate(f.template, f.output, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered = append(rendered, renderedFile{path: f.output, template: f.template, content: content})
+		}
+		return rendered, nil
+	}
+
+	entry := manifest.Service(target)
+	mem := newMemFS()
+	var files generatedFiles
+	var err error
+	switch entry.Template {
+	case TemplateAuth:
+		files, err = createAuthMicroservice(mem, projectGeneratorOptions(manifest), entry.Name, strconv.Itoa(entry.Port))
+	case TemplateGeneric:
+		entities, entitiesErr := serviceEntities(entry)
+		if entitiesErr != nil {
+			return nil, entitiesErr
+		}
+		files, err = createMicroservice(mem, serviceGeneratorOptions(manifest, entry), entry.Name, strconv.Itoa(entry.Port), "templates/", entities)
+	default:
+		return nil, fmt.Errorf("service '%s' uses template '%s', which this gores version cannot upgrade", entry.Name, entry.Template)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to render templates for '%s': %w", entry.Name, err)
+	}
+	return readRenderedFiles(mem, files)
+}
+
+// readRenderedFiles collects the generated files rendered into mem, sorted by path.
+func readRenderedFiles(mem *memFS, files generatedFiles) ([]renderedFile, error) {
+	var rendered []renderedFile
+	for file, tmpl := range files {
+		content, err := mem.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, renderedFile{path: file, template: tmpl, content: content})
+	}
+	sort.Slice(rendered, func(i, j int) bool { return rendered[i].path < rendered[j].path })
+	return rendered, nil
+}
+
+// upgradeFile decides how to bring one file up to date with its current rendering:
+// untouched files are replaced, edited files are merged with the baseline as the common
+// ancestor, and files without a baseline are only touched if they match exactly.
+func upgradeFile(idx *baselineIndex, f renderedFile, labels mergeLabels) upgradeResult {
+	r := upgradeResult{file: f.path, template: f.template, theirs: f.content}
+	base, hasBase := readBaseline(idx, f.path)
+
+	ours, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		if hasBase {
+			r.outcome, r.reason = upgradeSkipped, "deleted locally"
+			return r
+		}
+		r.outcome, r.write, r.recordBaseline = upgradeAdded, f.content, true
+		return r
+	} else if err != nil {
+		r.outcome, r.reason = upgradeSkipped, err.Error()
+		return r
+	}
+
+	switch {
+	case string(ours) == string(f.content):
+		r.outcome, r.recordBaseline = upgradeUnchanged, true
+	case !hasBase:
+		r.outcome, r.reason = upgradeSkipped, "edited and no baseline recorded"
+	case string(base) == string(f.content):
+		r.outcome = upgradeUnchanged // Template unchanged; keep the local edits.
+	case string(ours) == string(base):
+		r.outcome, r.write, r.recordBaseline = upgradeUpdated, f.content, true
+	default:
+		merged, conflicts := merge3(base, ours, f.content, labels)
+		r.write, r.recordBaseline, r.conflicts = merged, true, conflicts
+		r.outcome = upgradeMerged
+		if conflicts > 0 {
+			r.outcome = upgradeConflict
+		}
+	}
+	return r
+}
+
+// applyUpgradeResults writes the new content of every file and records the renderings
+// as the new baselines.
+func applyUpgradeResults(results []upgradeResult) error {
+	for _, r := range results {
+		if r.write == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(r.file), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(r.file), err)
+		}
+		if err := writeFileAtomic(r.file, r.write, 0644); err != nil {
+			return err
+		}
+	}
+
+	err := updateBaselines(func(idx *baselineIndex) error {
+		for _, r := range results {
+			if r.recordBaseline {
+				if err := putBaseline(idx, baselineKey(r.file), r.template, r.theirs); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("files were written but recording the new baselines failed: %w", err)
+	}
+	return nil
+}
+
+// printUpgradeSummary reports the outcome of every file that was not already up to date and
+// returns the number of files left with conflicts. title names the operation, e.g. "Upgrade".
+func printUpgradeSummary(title string, results []upgradeResult, dryRun bool) int {
+	if dryRun {
+		fmt.Println("Dry run: no files were written. The following changes would be made:")
+	}
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.outcome]++
+		switch r.outcome {
+		case upgradeUnchanged:
+			continue
+		case upgradeConflict:
+			fmt.Printf("  %-9s %s (%d conflicting hunk(s))\n", r.outcome, filepath.ToSlash(r.file), r.conflicts)
+		case upgradeSkipped:
+			fmt.Printf("  %-9s %s (%s)\n", r.outcome, filepath.ToSlash(r.file), r.reason)
+		default:
+			fmt.Printf("  %-9s %s\n", r.outcome, filepath.ToSlash(r.file))
+		}
+	}
+	fmt.Printf("%s summary: %d updated, %d merged, %d added, %d with conflicts, %d skipped, %d unchanged.\n",
+		title, counts[upgradeUpdated], counts[upgradeMerged], counts[upgradeAdded], counts[upgradeConflict],
+		counts[upgradeSkipped], counts[upgradeUnchanged])
+	return counts[upgradeConflict]
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradeDryRun, "dry-run", false, "Report what would be updated, merged or left in conflict without writing anything")
+	rootCmd.AddCommand(upgradeCmd)
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// olderLine is the line the templates of an "older gores version" had after the first one.
+const olderLine = "// Removed from the templates since.\n"
+
+// handEdit is a line added to a generated file by hand.
+const handEdit = "// Edited by hand.\n"
+
+// olderRendering stands in for what an older gores version rendered: the current rendering
+// with olderLine after its first line.
+func olderRendering(content []byte) []byte {
+	first, rest, _ := strings.Cut(string(content), "\n")
+	return []byte(first + "\n" + olderLine + rest)
+}
+
+// testBaseline returns the recorded baseline of file, or nil when there is none.
+func testBaseline(t *testing.T, file string) []byte {
+	t.Helper()
+	idx, err := loadBaselineIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := readBaseline(idx, file)
+	return content
+}
+
+func TestUpgradeOutcomes(t *testing.T) {
+	newOrdersProject(t)
+	rendered, err := renderUpgradeTarget(loadTestManifest(t), "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := map[string][]byte{}
+	for _, f := range rendered {
+		current[filepath.ToSlash(f.path)] = f.content
+	}
+
+	const (
+		untouched   = "services/orders/internal/controller.go"
+		edited      = "services/orders/internal/service.go"
+		conflicting = "services/orders/internal/router.go"
+		unrecorded  = "services/orders/internal/repository.go"
+		deleted     = "services/orders/internal/openapi.go"
+		added       = "services/orders/cmd/main.go"
+		kept        = "services/orders/internal/repository_memory.go"
+	)
+	older := map[string][]byte{}
+	for _, file := range []string{untouched, edited, conflicting, deleted} {
+		older[file] = olderRendering(current[file])
+	}
+	err = updateBaselines(func(idx *baselineIndex) error {
+		for file, content := range older {
+			if err := putBaseline(idx, file, idx.Files[file].Template, content); err != nil {
+				return err
+			}
+		}
+		dropBaseline(idx, unrecorded)
+		dropBaseline(idx, added)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{
+		untouched:   string(older[untouched]),
+		edited:      string(older[edited]) + handEdit,
+		conflicting: strings.Replace(string(older[conflicting]), olderLine, handEdit, 1),
+		unrecorded:  string(current[unrecorded]) + handEdit,
+		kept:        string(current[kept]) + handEdit,
+	} {
+		if err := os.WriteFile(filepath.FromSlash(file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{deleted, added} {
+		if err := os.Remove(filepath.FromSlash(file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		untouched:   upgradeUpdated,
+		edited:      upgradeMerged,
+		conflicting: upgradeConflict,
+		unrecorded:  upgradeSkipped,
+		deleted:     upgradeSkipped,
+		added:       upgradeAdded,
+	}
+	if got := upgradeOutcomes(t, "orders"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("outcomes = %v, want %v", got, want)
+	}
+
+	err = upgradeCmd.RunE(upgradeCmd, []string{"orders"})
+	if err == nil || !strings.Contains(err.Error(), "conflicts in 1 file") {
+		t.Fatalf("upgrade error = %v, want the conflict reported", err)
+	}
+
+	for _, tt := range []struct {
+		file           string
+		content        string // Empty when the file is absent
+		baseline       []byte // nil when no baseline is recorded
+		conflictMarker bool
+	}{
+		{file: untouched, content: string(current[untouched]), baseline: current[untouched]},
+		{file: edited, content: string(current[edited]) + handEdit, baseline: current[edited]},
+		{file: conflicting, baseline: current[conflicting], conflictMarker: true},
+		{file: unrecorded, content: string(current[unrecorded]) + handEdit},
+		{file: deleted, baseline: older[deleted]}, // Left deleted, with the old baseline kept
+		{file: added, content: string(current[added]), baseline: current[added]},
+		{file: kept, content: string(current[kept]) + handEdit, baseline: current[kept]},
+	} {
+		content, err := os.ReadFile(filepath.FromSlash(tt.file))
+		switch {
+		case tt.conflictMarker:
+			if !strings.Contains(string(content), "<<<<<<< yours") || !strings.Contains(string(content), handEdit) {
+				t.Errorf("%s lacks the conflict with the hand edit:\n%s", tt.file, content)
+			}
+		case tt.content == "":
+			if !os.IsNotExist(err) {
+				t.Errorf("%s was recreated (err: %v)", tt.file, err)
+			}
+		case string(content) != tt.content:
+			t.Errorf("%s =\n%s\nwant\n%s", tt.file, content, tt.content)
+		}
+		if got := testBaseline(t, tt.file); string(got) != string(tt.baseline) || (got == nil) != (tt.baseline == nil) {
+			t.Errorf("baseline of %s =\n%s\nwant\n%s", tt.file, got, tt.baseline)
+		}
+	}
+	if v := loadTestManifest(t).Service("orders").GoresVersion; v != Version {
+		t.Errorf("gores_version of orders = %q, want %q", v, Version)
+	}
+}
+
+func TestUpgradeDryRunWritesNothing(t *testing.T) {
+	newOrdersProject(t)
+	file := filepath.Join(servicesDir, "orders", "internal", "controller.go")
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = updateBaselines(func(idx *baselineIndex) error {
+		return putBaseline(idx, baselineKey(file), idx.Files[baselineKey(file)].Template, olderRendering(content))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, olderRendering(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	upgradeDryRun = true
+	t.Cleanup(func() { upgradeDryRun = false })
+	if err := upgradeCmd.RunE(upgradeCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(file); string(got) != string(olderRendering(content)) {
+		t.Errorf("--dry-run rewrote %s", file)
+	}
+	if got := testBaseline(t, file); string(got) != string(olderRendering(content)) {
+		t.Errorf("--dry-run recorded a new baseline of %s", file)
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// goDiagnostic matches the "file.go:line:col: message" lines printed by go build and go vet.
+var goDiagnostic = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::\d+)?: `)
+
+// missingModuleMarkers appear in go command output when a dependency is not in the local module cache.
+var missingModuleMarkers = []string{
+	"module lookup disabled by GOPROXY=off",
+	"cannot find module providing package",
+	"missing go.sum entry",
+}
+
+// verifyService builds and vets a freshly generated service together with the shared pkg
+// module. Everything runs against the local module cache (or a vendor/ directory), so it
+// works offline. Diagnostics in generated files are annotated with the template that
+// produced them.
+func verifyService(serviceName, framework string, files generatedFiles) error {
+	serviceDir := filepath.Join(servicesDir, serviceName)
+
+	// Shared pkg files may have been generated by an earlier 'gores init'.
+	sources := generatedFiles{}
+	for _, f := range pkgTemplates(framework, databaseNames) {
+		sources[filepath.Clean(f.output)] = f.template
+	}
+	for file, tmpl := range files {
+		sources[filepath.Clean(file)] = tmpl
+	}
+
+	fmt.Printf("Verifying service '%s' against the local module cache...\n", serviceName)
+
+	// Without go.work, the go commands run with -mod=mod, which would rewrite the project's
+	// go.mod and go.sum files. They run on a scratch copy of the two modules instead.
+	root := "."
+	if _, err := os.Stat(goWorkFile); os.IsNotExist(err) {
+		scratch, err := os.MkdirTemp("", "gores-verify-")
+		if err != nil {
+			return fmt.Errorf("failed to create a scratch directory: %w", err)
+		}
+		defer os.RemoveAll(scratch)
+		for _, dir := range []string{pkgModuleDir, serviceDir} {
+			if err := copyTree(dir, filepath.Join(scratch, dir)); err != nil {
+				return fmt.Errorf("failed to copy %s to a scratch directory: %w", dir, err)
+			}
+		}
+		root = scratch
+	}
+
+	// gRPC services only build once protoc has generated their code into gen/.
+	if _, err := os.Stat(filepath.Join(serviceDir, "proto", "generate.go")); err == nil {
+		if _, err := exec.LookPath("protoc"); err != nil {
+			return fmt.Errorf("service '%s' serves gRPC: install protoc (https://protobuf.dev/installation/) to generate and verify its code", serviceName)
+		}
+		if output, err := runOfflineGo(filepath.Join(root, serviceDir), "generate", "./proto"); err != nil {
+			fmt.Fprintf(os.Stderr, "'go generate ./proto' failed in %s:\n", serviceDir)
+			reportGoDiagnostics(os.Stderr, serviceDir, output, sources)
+			return fmt.Errorf("verification of service '%s' failed; the generated files were kept for inspection", serviceName)
+		}
+	}
+
+	failed := false
+	for _, dir := range []string{"pkg", serviceDir} {
+		// With -mod=mod, go build records the requirements it resolves from the cache in
+		// the scratch go.mod and go.sum. 'go mod tidy' is avoided: it also needs the test
+		// dependencies of every dependency, which are rarely cached.
+		steps := [][]string{{"build", "./..."}, {"vet", "./..."}}
+		if _, err := os.Stat(filepath.Join(root, dir, "vendor", "modules.txt")); err == nil {
+			steps = [][]string{{"build", "-mod=vendor", "./..."}, {"vet", "-mod=vendor", "./..."}}
+		}
+		for _, args := range steps {
+			output, err := runOfflineGo(filepath.Join(root, dir), args...)
+			if err == nil {
+				continue
+			}
+			failed = true
+			fmt.Fprintf(os.Stderr, "'go %s' failed in %s:\n", strings.Join(args, " "), dir)
+			reportGoDiagnostics(os.Stderr, dir, output, sources)
+			break // Later steps would only repeat the same errors.
+		}
+		if failed {
+			break
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("verification of service '%s' failed; the generated files were kept for inspection", serviceName)
+	}
+	fmt.Printf("Service '%s' builds and passes go vet. ✅\n", serviceName)
+	return nil
+}
+
+// copyTree copies the directory src with its files and subdirectories to dst.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+// runOfflineGo runs a go command in dir without network access and returns its combined output.
+// Without go.work in the working directory, the command may update the go.mod and go.sum
+// files of dir.
+func runOfflineGo(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	// An explicit -mod=vendor on the command line takes precedence over GOFLAGS.
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod", "GOWORK=off")
+	if workspace, err := filepath.Abs(goWorkFile); err == nil && !containsString(args, "-mod=vendor") {
+		if _, err := os.Stat(workspace); err == nil {
+			// Services without replace directives only resolve pkg through go.work, where
+			// -mod=mod is not allowed.
+			cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=", "GOWORK="+workspace)
+		}
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.Bytes(), err
+}
+
+// reportGoDiagnostics prints go command output to w, annotating every diagnostic that points
+// at a generated file with the template it was rendered from.
+func reportGoDiagnostics(w io.Writer, dir string, output []byte, sources generatedFiles) {
+	missingModules := false
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		for _, marker := range missingModuleMarkers {
+			if strings.Contains(line, marker) {
+				missingModules = true
+			}
+		}
+
+		match := goDiagnostic.FindStringSubmatch(line)
+		if match == nil {
+			fmt.Fprintf(w, "  %s\n", line)
+			continue
+		}
+		file := filepath.Clean(filepath.Join(dir, filepath.FromSlash(match[1])))
+		if tmpl, ok := sources[file]; ok {
+			line = strings.Replace(line, match[1], file, 1)
+			fmt.Fprintf(w, "  %s\n      (generated from %s)\n", line, tmpl)
+		} else {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	if missingModules {
+		fmt.Fprintln(w, "Hint: some dependencies are not in the local module cache. Run 'gores mod-tidy-all' once while online, or vendor them with 'go mod vendor'.")
+	}
+}
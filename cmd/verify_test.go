@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// skipUnlessServiceBuilds skips tests that run the go tool on a generated service when the
+// tool or the service's dependencies are not available offline.
+func skipUnlessServiceBuilds(t *testing.T, serviceName string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the generated service")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go tool is not installed")
+	}
+	if out, err := runOfflineGo(filepath.Join(servicesDir, serviceName), "vet", "./..."); err != nil {
+		t.Skipf("the generated service does not build from the local module cache: %v\n%s", err, out)
+	}
+}
+
+func TestReportGoDiagnostics(t *testing.T) {
+	dir := filepath.Join(servicesDir, "orders")
+	controller := filepath.Join(dir, "internal", "controller.go")
+	sources := generatedFiles{controller: "templates/controller.tmpl"}
+	output := "# orders/internal\n" +
+		"internal/controller.go:12:2: undefined: fiber\n" +
+		"vet: internal/controller.go:14:1: missing return\n" +
+		"internal/handwritten.go:3:1: syntax error\n" +
+		"go: module lookup disabled by GOPROXY=off\n"
+
+	var out bytes.Buffer
+	reportGoDiagnostics(&out, dir, []byte(output), sources)
+	for _, want := range []string{
+		"  " + controller + ":12:2: undefined: fiber\n      (generated from templates/controller.tmpl)\n",
+		"  vet: " + controller + ":14:1: missing return\n      (generated from templates/controller.tmpl)\n",
+		"  internal/handwritten.go:3:1: syntax error\n  go: module lookup",
+		"Hint: some dependencies are not in the local module cache.",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestVerifyServiceNamesBrokenTemplate(t *testing.T) {
+	newServicesProject(t, "orders")
+	skipUnlessServiceBuilds(t, "orders")
+
+	rendered, err := renderUpgradeTarget(loadTestManifest(t), "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := generatedFiles{}
+	for _, f := range rendered {
+		sources[f.path] = f.template
+	}
+	// A template that renders code which does not compile.
+	dir := filepath.Join(servicesDir, "orders")
+	controller := filepath.Join(dir, "internal", "controller.go")
+	content, err := os.ReadFile(controller)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(controller, append(content, "\nfunc broken() int { return \"broken\" }\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyService("orders", FrameworkFiber, sources); err == nil {
+		t.Fatal("verification of a broken service succeeded")
+	}
+	output, err := runOfflineGo(dir, "build", "./...")
+	if err == nil {
+		t.Fatal("the broken service builds")
+	}
+	var out bytes.Buffer
+	reportGoDiagnostics(&out, dir, output, sources)
+	want := "(generated from " + sources[controller] + ")"
+	if sources[controller] == "" || !strings.Contains(out.String(), controller+":") || !strings.Contains(out.String(), want) {
+		t.Errorf("report does not point at %s and its template %s:\n%s", controller, sources[controller], out.String())
+	}
+}
+
+func TestVerifyServiceWithoutWorkspaceLeavesModFiles(t *testing.T) {
+	// A project built without go.work resolves pkg through replace directives.
+	chdir(t, t.TempDir())
+	opts := generatorOptions{Module: defaultModulePath, Replace: true}
+	_, err := generateAll(
+		func() (generatedFiles, error) { return createSharedPkg(osFS{}, opts) },
+		func() (generatedFiles, error) {
+			return createMicroservice(osFS{}, opts, "orders", "8081", "templates/", nil)
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncWorkspace(osFS{}, []string{pkgModuleDir, serviceModuleDir("orders")}, true); err != nil {
+		t.Fatal(err)
+	}
+	skipUnlessServiceBuilds(t, "orders")
+	if err := os.Remove(goWorkFile); err != nil {
+		t.Fatal(err)
+	}
+
+	modFiles := map[string][]byte{}
+	for _, dir := range []string{"pkg", filepath.Join(servicesDir, "orders")} {
+		for _, name := range []string{"go.mod", "go.sum"} {
+			content, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			modFiles[filepath.Join(dir, name)] = content
+		}
+	}
+
+	if err := verifyService("orders", FrameworkFiber, nil); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range modFiles {
+		if got, _ := os.ReadFile(file); string(got) != string(want) {
+			t.Errorf("verification rewrote %s:\n%s", file, got)
+		}
+	}
+}
+
+func TestRegenerateFromSchemaVerifies(t *testing.T) {
+	newServicesProject(t, "orders")
+	skipUnlessServiceBuilds(t, "orders")
+	schema := "service: orders\nentities:\n  - name: orders\n    fields:\n      - note:string\n"
+	if err := os.WriteFile("orders.yaml", []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A local edit that breaks the build, which regeneration keeps.
+	service := filepath.Join(servicesDir, "orders", "internal", "service.go")
+	content, err := os.ReadFile(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(service, append(content, "\nfunc broken() int { return \"broken\" }\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	generateFrom, generateVerify = "orders.yaml", true
+	t.Cleanup(func() { generateFrom, generateVerify = "", false })
+	err = generateCmd.RunE(generateCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "verification of service 'orders' failed") {
+		t.Fatalf("regeneration with --verify = %v, want the verification to fail", err)
+	}
+	if entry := loadTestManifest(t).Service("orders"); entry.Schema != "orders.yaml" {
+		t.Errorf("the service was not regenerated from the schema: %+v", entry)
+	}
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	goWorkFile = "go.work"
+
+	// workspaceGoVersion is the go directive of a new go.work; it matches the generated modules.
+	workspaceGoVersion = "1.24"
+
+	// pkgModuleDir is the go.work-style relative path of the shared pkg module.
+	pkgModuleDir = "./pkg"
+)
+
+// composeFiles are the Docker Compose file names looked up at the project root.
+var composeFiles = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
+
+// serviceModuleDir returns the go.work-style relative path of a service module.
+func serviceModuleDir(serviceName string) string {
+	return "./" + filepath.ToSlash(filepath.Join(servicesDir, serviceName))
+}
+
+// updateWorkspace parses go.work, applies edit to it and writes it back when edit reports
+// a change. When create is set, a missing go.work is created; otherwise a project without
+// go.work is left alone. On disk, this happens under the project lock and go.work is
+// replaced atomically. It reports whether go.work was written.
+func updateWorkspace(fsys projectFS, create bool, edit func(work *modfile.WorkFile) (bool, error)) (bool, error) {
+	written := false
+	update := func() error {
+		content, err := fsys.ReadFile(goWorkFile)
+		if os.IsNotExist(err) {
+			if !create {
+				return nil
+			}
+			content = []byte("go " + workspaceGoVersion + "\n")
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", goWorkFile, err)
+		}
+
+		work, err := modfile.ParseWork(goWorkFile, content, nil)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", goWorkFile, err)
+		}
+		if changed, err := edit(work); err != nil || !changed {
+			return err
+		}
+		work.SortBlocks()
+		work.Cleanup()
+
+		write := fsys.WriteFile
+		if _, onDisk := fsys.(osFS); onDisk {
+			write = writeFileAtomic
+		}
+		if err := write(goWorkFile, modfile.Format(work.Syntax), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", goWorkFile, err)
+		}
+		written = true
+		return nil
+	}
+
+	if _, onDisk := fsys.(osFS); !onDisk {
+		return written, update()
+	}
+	err := withProjectLock(".", update)
+	return written, err
+}
+
+// syncWorkspace adds a 'use' directive to go.work for every module directory in dirs that
+// is not listed yet. When create is set, a missing go.work is created; otherwise a project
+// without go.work is left alone. It reports whether go.work was written.
+func syncWorkspace(fsys projectFS, dirs []string, create bool) (bool, error) {
+	return updateWorkspace(fsys, create, func(work *modfile.WorkFile) (bool, error) {
+		used := map[string]bool{}
+		for _, use := range work.Use {
+			used[filepath.Clean(use.Path)] = true
+		}
+
+		changed := false
+		for _, dir := range dirs {
+			if used[filepath.Clean(dir)] {
+				continue
+			}
+			if err := work.AddUse(dir, ""); err != nil {
+				return false, fmt.Errorf("failed to add '%s' to %s: %w", dir, goWorkFile, err)
+			}
+			used[filepath.Clean(dir)] = true
+			changed = true
+		}
+		return changed, nil
+	})
+}
+
+// dropWorkspaceUse removes the 'use' directive for dir from go.work. It reports whether
+// go.work existed and was changed.
+func dropWorkspaceUse(dir string) (bool, error) {
+	return updateWorkspace(osFS{}, false, func(work *modfile.WorkFile) (bool, error) {
+		return dropUse(work, dir)
+	})
+}
+
+// renameWorkspaceUse replaces the 'use' directive for oldDir with newDir in go.work. It
+// reports whether go.work existed and was changed.
+func renameWorkspaceUse(oldDir, newDir string) (bool, error) {
+	return updateWorkspace(osFS{}, false, func(work *modfile.WorkFile) (bool, error) {
+		if changed, err := dropUse(work, oldDir); err != nil || !changed {
+			return false, err
+		}
+		if err := work.AddUse(newDir, ""); err != nil {
+			return false, fmt.Errorf("failed to add '%s' to %s: %w", newDir, goWorkFile, err)
+		}
+		return true, nil
+	})
+}
+
+// dropUse removes the 'use' directives for dir from work and reports whether there were any.
+func dropUse(work *modfile.WorkFile, dir string) (bool, error) {
+	changed := false
+	for _, use := range work.Use {
+		if filepath.Clean(use.Path) == filepath.Clean(dir) {
+			if err := work.DropUse(use.Path); err != nil {
+				return false, fmt.Errorf("failed to drop '%s' from %s: %w", use.Path, goWorkFile, err)
+			}
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// dropComposeService removes the named service, and any depends_on entries pointing at
+// it, from the project's Docker Compose files. It returns the files that were changed.
+func dropComposeService(serviceName string) ([]string, error) {
+	return editComposeFiles(func(services *yaml.Node) bool {
+		changed := deleteMappingKey(services, serviceName)
+		for i := 1; i < len(services.Content); i += 2 {
+			service := services.Content[i]
+			dependsOn := mappingValue(service, "depends_on")
+			if dependsOn == nil {
+				continue
+			}
+			switch dependsOn.Kind {
+			case yaml.SequenceNode:
+				kept := dependsOn.Content[:0]
+				for _, item := range dependsOn.Content {
+					if item.Value == serviceName {
+						changed = true
+						continue
+					}
+					kept = append(kept, item)
+				}
+				dependsOn.Content = kept
+			case yaml.MappingNode:
+				if deleteMappingKey(dependsOn, serviceName) {
+					changed = true
+				}
+			}
+			if len(dependsOn.Content) == 0 {
+				deleteMappingKey(service, "depends_on")
+			}
+		}
+		return changed
+	})
+}
+
+// renameComposeService renames a service key, the depends_on entries pointing at it and
+// any 'services/<old>' build paths in the project's Docker Compose files. It returns the
+// files that were changed.
+func renameComposeService(oldName, newName string) ([]string, error) {
+	oldPath := servicesDir + "/" + oldName
+	newPath := servicesDir + "/" + newName
+
+	return editComposeFiles(func(services *yaml.Node) bool {
+		changed := false
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			key, service := services.Content[i], services.Content[i+1]
+			if key.Value == oldName {
+				key.Value = newName
+				changed = true
+			}
+			if dependsOn := mappingValue(service, "depends_on"); dependsOn != nil {
+				for j, item := range dependsOn.Content {
+					// Sequences hold names directly; mappings hold them at even (key) positions.
+					if item.Value == oldName && (dependsOn.Kind == yaml.SequenceNode || j%2 == 0) {
+						item.Value = newName
+						changed = true
+					}
+				}
+			}
+			walkScalars(service, func(n *yaml.Node) {
+				changed = set(&n.Value, replacePathPrefix(n.Value, oldPath, newPath)) || changed
+			})
+		}
+		return changed
+	})
+}
+
+// replacePathPrefix replaces the occurrences of the path old in s that are followed by a
+// "/" or the end of s, so that services/orders-v2 is left alone when renaming services/orders.
+func replacePathPrefix(s, old, new string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		rest := s[i+len(old):]
+		b.WriteString(s[:i])
+		if rest == "" || rest[0] == '/' {
+			b.WriteString(new)
+		} else {
+			b.WriteString(old)
+		}
+		s = rest
+	}
+}
+
+// editComposeFiles applies edit to the top-level 'services' mapping of every Docker
+// Compose file in the project and rewrites the files for which edit reports a change.
+func editComposeFiles(edit func(services *yaml.Node) bool) ([]string, error) {
+	var changedFiles []string
+	for _, file := range composeFiles {
+		content, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return changedFiles, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return changedFiles, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		services := mappingValue(doc.Content[0], "services")
+		if services == nil || services.Kind != yaml.MappingNode {
+			continue
+		}
+		if !edit(services) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return changedFiles, fmt.Errorf("failed to encode %s: %w", file, err)
+		}
+		enc.Close()
+		if err := writeFileAtomic(file, buf.Bytes(), 0644); err != nil {
+			return changedFiles, err
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
+
+// walkScalars calls fn for every scalar node below node.
+func walkScalars(node *yaml.Node, fn func(n *yaml.Node)) {
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.ScalarNode {
+		fn(node)
+		return
+	}
+	for _, child := range node.Content {
+		walkScalars(child, fn)
+	}
+}
+
+// mappingValue returns the value node stored under key in a YAML mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// deleteMappingKey removes key and its value from a YAML mapping node.
+func deleteMappingKey(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+// workspaceUses lists the sorted 'use' directories of go.work.
+func workspaceUses(t *testing.T) []string {
+	t.Helper()
+	content, err := os.ReadFile(goWorkFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, err := modfile.ParseWork(goWorkFile, content, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for _, use := range work.Use {
+		dirs = append(dirs, filepath.ToSlash(use.Path))
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// concurrently runs fn for 0..n-1 in parallel goroutines and reports its errors.
+func concurrently(t *testing.T, n int, fn func(i int) error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := fn(i); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestWorkspaceEditsConcurrently(t *testing.T) {
+	chdir(t, t.TempDir())
+	const workers = 16
+
+	// Every concurrent edit of go.work is kept.
+	concurrently(t, workers, func(i int) error {
+		_, err := syncWorkspace(osFS{}, []string{serviceModuleDir(fmt.Sprintf("svc-%02d", i))}, true)
+		return err
+	})
+	var want []string
+	for i := 0; i < workers; i++ {
+		want = append(want, fmt.Sprintf("./services/svc-%02d", i))
+	}
+	if got := workspaceUses(t); !reflect.DeepEqual(got, want) {
+		t.Fatalf("go.work uses %v, want %v", got, want)
+	}
+
+	// Half the services are removed while the other half are renamed.
+	concurrently(t, workers, func(i int) error {
+		name := fmt.Sprintf("svc-%02d", i)
+		if i%2 == 0 {
+			_, err := dropWorkspaceUse(serviceModuleDir(name))
+			return err
+		}
+		_, err := renameWorkspaceUse(serviceModuleDir(name), serviceModuleDir("renamed-"+name))
+		return err
+	})
+	want = nil
+	for i := 1; i < workers; i += 2 {
+		want = append(want, fmt.Sprintf("./services/renamed-svc-%02d", i))
+	}
+	if got := workspaceUses(t); !reflect.DeepEqual(got, want) {
+		t.Fatalf("go.work uses %v, want %v", got, want)
+	}
+
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != goWorkFile && e.Name() != lockFile {
+			t.Errorf("unexpected file %s left next to %s", e.Name(), goWorkFile)
+		}
+	}
+}
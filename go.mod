@@ -2,9 +2,15 @@ module github.com/Muhammad-Ali-Khan9/gores
 
 go 1.20
 
-require github.com/spf13/cobra v1.7.0
+require (
+	github.com/gofrs/flock v0.8.1
+	github.com/spf13/cobra v1.7.0
+	golang.org/x/mod v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.10.0 // indirect
 )